@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// flushOutput is replaced by annotateOutput, and must be called before exiting.
+var flushOutput = func() {}
+
+func exit(code int) {
+	flushOutput()
+	os.Exit(code)
+}
+
+// annotateOutput redirects stdout through a pipe, so that the warnings and errors printed by the tool are written as
+// Github Actions workflow commands, and show up as annotations in the Actions UI.
+func annotateOutput() error {
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	os.Stdout = w
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			fmt.Fprintln(stdout, annotate(scanner.Text()))
+		}
+	}()
+	flushOutput = func() {
+		w.Close()
+		<-done
+		os.Stdout = stdout
+	}
+	return nil
+}
+
+// annotate turns a line starting with "Warning: ", "Error: " or "Failed ", or a warning or error logged with slog,
+// into a workflow command.
+func annotate(line string) string {
+	if command, msg, ok := parseSlogLine(line); ok {
+		return command + escapeWorkflowCommand(msg)
+	}
+	if msg, ok := strings.CutPrefix(line, "Warning: "); ok {
+		return "::warning::" + escapeWorkflowCommand(msg)
+	}
+	if msg, ok := strings.CutPrefix(line, "Error: "); ok {
+		return "::error::" + escapeWorkflowCommand(msg)
+	}
+	if strings.HasPrefix(line, "Failed ") {
+		return "::error::" + escapeWorkflowCommand(line)
+	}
+	return line
+}
+
+func escapeWorkflowCommand(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+var slogTextLine = regexp.MustCompile(`^time=\S+ level=(WARN|ERROR) (.*)$`)
+
+// slogCommands are the workflow commands of the slog levels that are annotated.
+var slogCommands = map[string]string{
+	"WARN":  "::warning::",
+	"ERROR": "::error::",
+}
+
+// parseSlogLine returns the workflow command and message of a warning or error written by slog's text or JSON
+// handler. The message includes the attributes, e.g. msg="Copy failed" repo=x.
+func parseSlogLine(line string) (command, msg string, ok bool) {
+	if m := slogTextLine.FindStringSubmatch(line); m != nil {
+		return slogCommands[m[1]], m[2], true
+	}
+	if !strings.HasPrefix(line, "{") {
+		return "", "", false
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return "", "", false
+	}
+	level, _ := record["level"].(string)
+	if command, ok = slogCommands[level]; !ok {
+		return "", "", false
+	}
+	msg = fmt.Sprint(record["msg"])
+	var keys []string
+	for k := range record {
+		if k != "time" && k != "level" && k != "msg" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		msg += fmt.Sprintf(" %s=%v", k, record[k])
+	}
+	return command, msg, true
+}
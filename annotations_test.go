@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestAnnotate(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "Warning: skipping \"repo\"", want: "::warning::skipping \"repo\""},
+		{line: "Error: 50% failed", want: "::error::50%25 failed"},
+		{line: "Failed to copy \"repo\"", want: "::error::Failed to copy \"repo\""},
+		{line: "Copying \"repo\"...", want: "Copying \"repo\"..."},
+	}
+	for _, tt := range tests {
+		if got := annotate(tt.line); got != tt.want {
+			t.Errorf("annotate(%q): expected %q, got %q", tt.line, tt.want, got)
+		}
+	}
+}
+
+func TestAnnotateSlog(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			var handler slog.Handler = slog.NewTextHandler(&buf, nil)
+			if format == "json" {
+				handler = slog.NewJSONHandler(&buf, nil)
+			}
+			log := slog.New(handler)
+			log.Info("Git transfer complete", "repo", "a")
+			log.Warn("Slow push", "repo", "b")
+			log.Error("Copy failed", "repo", "c")
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("expected 3 lines, got %q", lines)
+			}
+			if got := annotate(lines[0]); got != lines[0] {
+				t.Errorf("expected info to be unchanged, got %q", got)
+			}
+			if got := annotate(lines[1]); !strings.HasPrefix(got, "::warning::") || !strings.Contains(got, "Slow push") || !strings.Contains(got, "repo=b") {
+				t.Errorf("expected a warning annotation, got %q", got)
+			}
+			if got := annotate(lines[2]); !strings.HasPrefix(got, "::error::") || !strings.Contains(got, "Copy failed") || !strings.Contains(got, "repo=c") {
+				t.Errorf("expected an error annotation, got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// apiStats counts the Github API calls made to each host, and records the rate limit returned by the most recent call.
+type apiStats struct {
+	m     sync.Mutex
+	hosts map[string]*hostAPIStats
+}
+
+type hostAPIStats struct {
+	Calls     int
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+func newAPIStats() *apiStats {
+	return &apiStats{
+		hosts: make(map[string]*hostAPIStats),
+	}
+}
+
+func (s *apiStats) Reset() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.hosts = make(map[string]*hostAPIStats)
+}
+
+func (s *apiStats) Record(host string, header http.Header) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	hs, ok := s.hosts[host]
+	if !ok {
+		hs = &hostAPIStats{}
+		s.hosts[host] = hs
+	}
+	hs.Calls++
+	if v, err := strconv.Atoi(header.Get("X-RateLimit-Limit")); err == nil {
+		hs.Limit = v
+	}
+	if v, err := strconv.Atoi(header.Get("X-RateLimit-Remaining")); err == nil {
+		hs.Remaining = v
+	}
+	if v, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		hs.Reset = time.Unix(v, 0).UTC()
+	}
+}
+
+// Print the API usage for each host, and warn if running again after the given interval would exhaust the rate limit.
+func (s *apiStats) Print(every time.Duration) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	hosts := make([]string, 0, len(s.hosts))
+	for host := range s.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		hs := s.hosts[host]
+		if hs.Limit == 0 {
+			fmt.Printf("%s: API calls this run: %d\n", host, hs.Calls)
+			continue
+		}
+		fmt.Printf("%s: API calls this run: %d, rate limit remaining: %d, resets at: %s\n", host, hs.Calls, hs.Remaining, hs.Reset.Format(time.RFC3339))
+		if every <= 0 {
+			continue
+		}
+		// Rate limits are applied per hour.
+		runsPerHour := float64(time.Hour) / float64(every)
+		if float64(hs.Calls)*runsPerHour > float64(hs.Limit) {
+			suggested := time.Duration(float64(time.Hour) * float64(hs.Calls) / float64(hs.Limit)).Round(time.Minute)
+			fmt.Printf("Warning: %s: running every %v uses more than the rate limit of %d calls per hour, consider an interval of at least %v\n", host, every, hs.Limit, suggested)
+		}
+	}
+}
+
+type countingTransport struct {
+	stats *apiStats
+	next  http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	t.stats.Record(req.URL.Host, resp.Header)
+	return resp, nil
+}
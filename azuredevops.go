@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const azureDevOpsAPIVersion = "7.0"
+
+// azureDevOpsRepoURL returns the URL of a repo in an Azure DevOps project, e.g.
+// https://dev.azure.com/org/project/_git/name, given the URL of the project.
+func azureDevOpsRepoURL(projectURL, name string) (repoURL string, err error) {
+	u, err := url.Parse(projectURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse target URL: %w", err)
+	}
+	owner := strings.Trim(u.Path, "/")
+	if len(strings.Split(owner, "/")) != 2 {
+		return "", fmt.Errorf("expected an Azure DevOps project URL, e.g. https://dev.azure.com/org/project, got %q", projectURL)
+	}
+	p := "/" + owner + "/_git/" + name
+	u = &url.URL{
+		Scheme:  u.Scheme,
+		Host:    u.Host,
+		Path:    p,
+		RawPath: p,
+	}
+	return u.String(), nil
+}
+
+// parseAzureDevOpsRepoURL returns the org/project and name of a repo URL, e.g.
+// https://dev.azure.com/org/project/_git/name
+func parseAzureDevOpsRepoURL(u *url.URL) (owner, name string, err error) {
+	owner, name, ok := strings.Cut(strings.Trim(u.Path, "/"), "/_git/")
+	if !ok || len(strings.Split(owner, "/")) != 2 || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("expected an Azure DevOps repo URL, e.g. https://dev.azure.com/org/project/_git/name, got %q", u)
+	}
+	return owner, name, nil
+}
+
+type azureDevOpsRepo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	WebURL        string `json:"webUrl"`
+	DefaultBranch string `json:"defaultBranch"`
+	Size          int    `json:"size"`
+	IsDisabled    bool   `json:"isDisabled"`
+}
+
+// Repo converts the repo. Disabled repos can't be pushed to, like archived Github repos.
+func (r azureDevOpsRepo) Repo() Repo {
+	return Repo{
+		Name: r.Name,
+		URL:  r.WebURL,
+		// Azure DevOps reports the size in bytes.
+		Size:          r.Size / 1024,
+		Archived:      r.IsDisabled,
+		DefaultBranch: strings.TrimPrefix(r.DefaultBranch, "refs/heads/"),
+	}
+}
+
+// AzureDevOpsClient is a RepoClient for Azure DevOps Services and Server, using the REST API. The owner of a repo is
+// the org (or collection) and project, e.g. org/project.
+type AzureDevOpsClient struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+}
+
+func newAzureDevOpsClient(clients *clientFactory, u *url.URL, token string) *AzureDevOpsClient {
+	return &AzureDevOpsClient{
+		httpClient: clients.NewHTTPClient(),
+		baseURL:    u.Scheme + "://" + u.Host,
+		token:      token,
+	}
+}
+
+// ListRepos lists the repos of a project, where owner is in the form org/project.
+func (c *AzureDevOpsClient) ListRepos(ctx context.Context, owner, ownerType string) (repos []Repo, err error) {
+	var page struct {
+		Value []azureDevOpsRepo `json:"value"`
+	}
+	// The API returns all of the repos in a project at once.
+	if _, err = c.do(ctx, http.MethodGet, owner+"/_apis/git/repositories", nil, &page); err != nil {
+		return repos, err
+	}
+	for _, r := range page.Value {
+		repos = append(repos, r.Repo())
+	}
+	return repos, nil
+}
+
+func (c *AzureDevOpsClient) GetRepo(ctx context.Context, owner, name string) (r Repo, ok bool, err error) {
+	var repo azureDevOpsRepo
+	status, err := c.do(ctx, http.MethodGet, owner+"/_apis/git/repositories/"+url.PathEscape(name), nil, &repo)
+	if status == http.StatusNotFound {
+		return r, false, nil
+	}
+	if err != nil {
+		return r, false, err
+	}
+	return repo.Repo(), true, nil
+}
+
+// CreateRepo creates a repo in the project. Azure DevOps repos don't have a description, and get their visibility
+// from the project, so only the name is used.
+func (c *AzureDevOpsClient) CreateRepo(ctx context.Context, owner string, r Repo) error {
+	org, project, _ := strings.Cut(owner, "/")
+	var p struct {
+		ID string `json:"id"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, org+"/_apis/projects/"+url.PathEscape(project), nil, &p); err != nil {
+		return fmt.Errorf("failed to get project %q: %w", project, err)
+	}
+	body := map[string]any{
+		"name":    r.Name,
+		"project": map[string]string{"id": p.ID},
+	}
+	status, err := c.do(ctx, http.MethodPost, owner+"/_apis/git/repositories", body, nil)
+	if status == http.StatusConflict {
+		return fmt.Errorf("%w: %v", errRepoExists, err)
+	}
+	return err
+}
+
+// EditRepo updates the default branch of the repo, and disables it if updates is archived. Azure DevOps repos don't
+// have a description or their own visibility, so they're ignored.
+func (c *AzureDevOpsClient) EditRepo(ctx context.Context, owner, name string, updates Repo) error {
+	var repo azureDevOpsRepo
+	if _, err := c.do(ctx, http.MethodGet, owner+"/_apis/git/repositories/"+url.PathEscape(name), nil, &repo); err != nil {
+		return err
+	}
+	body := map[string]any{}
+	if updates.DefaultBranch != "" {
+		body["defaultBranch"] = "refs/heads/" + updates.DefaultBranch
+	}
+	if updates.Archived {
+		body["isDisabled"] = true
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	_, err := c.do(ctx, http.MethodPatch, owner+"/_apis/git/repositories/"+url.PathEscape(repo.ID), body, nil)
+	return err
+}
+
+// DeleteRepo deletes the repo, which Azure DevOps keeps in the project's recycle bin for 30 days.
+func (c *AzureDevOpsClient) DeleteRepo(ctx context.Context, owner, name string) error {
+	var repo azureDevOpsRepo
+	if _, err := c.do(ctx, http.MethodGet, owner+"/_apis/git/repositories/"+url.PathEscape(name), nil, &repo); err != nil {
+		return err
+	}
+	_, err := c.do(ctx, http.MethodDelete, owner+"/_apis/git/repositories/"+url.PathEscape(repo.ID), nil, nil)
+	return err
+}
+
+// do makes a request to the API, and decodes the response into v. The status code is returned, so that callers can
+// handle expected errors.
+func (c *AzureDevOpsClient) do(ctx context.Context, method, path string, body, v any) (status int, err error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	u := c.baseURL + "/" + path + "?api-version=" + azureDevOpsAPIVersion
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	// Personal access tokens are passed as the password, with an empty username.
+	req.SetBasicAuth("", c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to %s %s: %w", method, u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return resp.StatusCode, fmt.Errorf("%s %s: unexpected status %s: %s", method, u, resp.Status, e.Message)
+	}
+	if v == nil {
+		return resp.StatusCode, nil
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
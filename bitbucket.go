@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const bitbucketAPIURL = "https://api.bitbucket.org/2.0"
+
+type bitbucketRepoPage struct {
+	Values []bitbucketRepo `json:"values"`
+	Next   string          `json:"next"`
+}
+
+type bitbucketRepo struct {
+	Slug      string    `json:"slug"`
+	SCM       string    `json:"scm"`
+	CreatedOn time.Time `json:"created_on"`
+	Links     struct {
+		Clone []struct {
+			Href string `json:"href"`
+			Name string `json:"name"`
+		} `json:"clone"`
+	} `json:"links"`
+}
+
+// listBitbucketRepos lists the git repos in a Bitbucket Cloud workspace, e.g. https://bitbucket.org/workspace
+func listBitbucketRepos(ctx context.Context, clients *clientFactory, bbURL, token string) (repos []Repo, err error) {
+	u, err := url.Parse(bbURL)
+	if err != nil {
+		return repos, fmt.Errorf("failed to parse url: %w", err)
+	}
+	workspace := strings.Split(strings.Trim(u.Path, "/"), "/")[0]
+	if workspace == "" {
+		return repos, fmt.Errorf("expected a Bitbucket workspace URL, e.g. https://bitbucket.org/workspace, got %q", bbURL)
+	}
+	httpClient := clients.NewHTTPClient()
+
+	next := bitbucketAPIURL + "/repositories/" + url.PathEscape(workspace) + "?pagelen=100"
+	for next != "" {
+		var page bitbucketRepoPage
+		if page, err = getBitbucketRepoPage(ctx, httpClient, next, token); err != nil {
+			return repos, err
+		}
+		for _, r := range page.Values {
+			if r.SCM != "git" || len(r.Links.Clone) == 0 {
+				continue
+			}
+			cloneURL := r.Links.Clone[0].Href
+			for _, c := range r.Links.Clone {
+				if c.Name == "https" {
+					cloneURL = c.Href
+				}
+			}
+			repos = append(repos, Repo{
+				Name:      r.Slug,
+				URL:       stripUserInfo(cloneURL),
+				CreatedAt: r.CreatedOn,
+			})
+		}
+		// Bitbucket uses cursor based pagination, the next field is empty on the last page.
+		next = page.Next
+	}
+	return repos, nil
+}
+
+func getBitbucketRepoPage(ctx context.Context, httpClient *http.Client, pageURL, token string) (page bitbucketRepoPage, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return page, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return page, fmt.Errorf("failed to list repos: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return page, fmt.Errorf("failed to list repos: unexpected status %d", resp.StatusCode)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return page, fmt.Errorf("failed to decode repos: %w", err)
+	}
+	return page, nil
+}
+
+// stripUserInfo removes the username that Bitbucket includes in clone URLs, since the credentials are set separately.
+func stripUserInfo(cloneURL string) string {
+	u, err := url.Parse(cloneURL)
+	if err != nil {
+		return cloneURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// gitUsername returns the username used with an access token for the host of a git URL.
+func gitUsername(gitURL string) string {
+	u, err := url.Parse(gitURL)
+	if err == nil && strings.EqualFold(u.Hostname(), "bitbucket.org") {
+		return "x-token-auth"
+	}
+	return "git"
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// browserAuth gets a token for the Github server at ghURL using the OAuth web flow with PKCE. The user signs in with
+// their browser, which redirects back to a server listening on localhost. Tokens are cached in the user's config
+// directory, and reused while they're still valid.
+func browserAuth(ctx context.Context, clients *clientFactory, ghURL, clientID, clientSecret string) (token string, err error) {
+	u, err := url.Parse(ghURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	host := strings.ToLower(u.Host)
+
+	cache := loadTokenCache()
+	if token = cache[host]; token != "" {
+		client, err := clients.NewGitHubClient(u, token)
+		if err != nil {
+			return "", err
+		}
+		if _, _, err = client.Users.Get(ctx, ""); err == nil {
+			return token, nil
+		}
+		fmt.Printf("Cached token for %s is no longer valid, signing in again.\n", host)
+	}
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for the OAuth redirect: %w", err)
+	}
+	redirectURL := "http://" + listener.Addr().String() + "/callback"
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/callback" {
+				http.NotFound(w, r)
+				return
+			}
+			q := r.URL.Query()
+			if q.Get("state") != state {
+				http.Error(w, "Invalid state.", http.StatusBadRequest)
+				return
+			}
+			if e := q.Get("error"); e != "" {
+				http.Error(w, "Sign in failed: "+e, http.StatusBadRequest)
+				errs <- fmt.Errorf("sign in failed: %s: %s", e, q.Get("error_description"))
+				return
+			}
+			fmt.Fprintln(w, "Signed in to copy-github-to-github, you can close this window.")
+			codes <- q.Get("code")
+		}),
+	}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	authURL := &url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   "/login/oauth/authorize",
+		RawQuery: url.Values{
+			"client_id":             {clientID},
+			"redirect_uri":          {redirectURL},
+			"scope":                 {"repo"},
+			"state":                 {state},
+			"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+			"code_challenge_method": {"S256"},
+		}.Encode(),
+	}
+	fmt.Printf("Opening your browser to sign in. If it doesn't open, visit:\n%s\n", authURL)
+	if err = openBrowser(authURL.String()); err != nil {
+		fmt.Printf("Warning: failed to open browser: %v\n", err)
+	}
+
+	var code string
+	select {
+	case code = <-codes:
+	case err = <-errs:
+		return "", err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	if token, err = exchangeOAuthCode(ctx, u, clientID, clientSecret, code, redirectURL, verifier); err != nil {
+		return "", err
+	}
+	cache[host] = token
+	if err = cache.Save(); err != nil {
+		fmt.Printf("Warning: failed to cache token: %v\n", err)
+	}
+	return token, nil
+}
+
+func exchangeOAuthCode(ctx context.Context, u *url.URL, clientID, clientSecret, code, redirectURL, verifier string) (token string, err error) {
+	form := url.Values{
+		"client_id":     {clientID},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {verifier},
+	}
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+	tokenURL := u.Scheme + "://" + u.Host + "/login/oauth/access_token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange OAuth code: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode OAuth token response: %w", err)
+	}
+	if body.Error != "" {
+		return "", fmt.Errorf("failed to exchange OAuth code: %s: %s", body.Error, body.ErrorDescription)
+	}
+	if body.AccessToken == "" {
+		return "", fmt.Errorf("OAuth token response did not include an access token")
+	}
+	return body.AccessToken, nil
+}
+
+func randomString(n int) (s string, err error) {
+	b := make([]byte, n)
+	if _, err = rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func openBrowser(u string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", u).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", u).Start()
+	}
+	return exec.Command("xdg-open", u).Start()
+}
+
+// tokenCache maps hosts to OAuth tokens. It's only readable by the current user.
+type tokenCache map[string]string
+
+func tokenCacheFileName() (fileName string, err error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "copy-github-to-github", "tokens.json"), nil
+}
+
+func loadTokenCache() tokenCache {
+	cache := make(tokenCache)
+	fileName, err := tokenCacheFileName()
+	if err != nil {
+		return cache
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("Warning: failed to read token cache: %v\n", err)
+		}
+		return cache
+	}
+	if err = json.Unmarshal(data, &cache); err != nil {
+		fmt.Printf("Warning: token cache %q is corrupted, ignoring it: %v\n", fileName, err)
+		return make(tokenCache)
+	}
+	return cache
+}
+
+func (c tokenCache) Save() error {
+	fileName, err := tokenCacheFileName()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(fileName), 0o700); err != nil {
+		return fmt.Errorf("failed to create token cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal token cache: %w", err)
+	}
+	// Temp files are created with 0600 permissions.
+	return writeFileAtomic(fileName, data)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Set at build time, e.g. go build -ldflags "-X main.version=v1.2.3 -X main.commit=abc1234 -X main.date=2024-01-15".
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+// buildInfo returns the commit and build date, falling back to the VCS information embedded by the Go toolchain.
+func buildInfo() (c, d string) {
+	c, d = commit, date
+	if c != "" && d != "" {
+		return c, d
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && c == "":
+				c = s.Value[:min(len(s.Value), 7)]
+			case s.Key == "vcs.time" && d == "":
+				d = s.Value[:min(len(s.Value), 10)]
+			}
+		}
+	}
+	if c == "" {
+		c = "unknown"
+	}
+	if d == "" {
+		d = "unknown"
+	}
+	return c, d
+}
+
+// versionString returns e.g. "copy-github-to-github v1.2.3 (commit abc1234, built 2024-01-15)".
+func versionString() string {
+	c, d := buildInfo()
+	return fmt.Sprintf("copy-github-to-github %s (commit %s, built %s)", version, c, d)
+}
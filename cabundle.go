@@ -0,0 +1,38 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// useCABundles returns a transport that trusts the certificates in the PEM files, as well as the system CAs, and
+// installs it for go-git's HTTPS operations. go-git's protocols are global, so the CAs of the source and target are
+// trusted for both.
+func useCABundles(paths ...string) (transport *http.Transport, err error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		pem, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %q", p)
+		}
+	}
+	transport = http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	client.InstallProtocol("https", githttp.NewClient(&http.Client{Transport: transport}))
+	return transport, nil
+}
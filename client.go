@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// clientOptions configures the HTTP clients used for API requests and git operations. The sub-commands share them, so
+// that they can all connect to servers with an internal CA.
+type clientOptions struct {
+	APICallsPerSecond        float64
+	MaxConcurrentAPIRequests int
+	APITimeout               time.Duration
+	UserAgent                string
+	SrcCABundle              string
+	TgtCABundle              string
+	ValidateConnectivity     bool
+}
+
+func (o *clientOptions) RegisterFlags(fs *flag.FlagSet) {
+	fs.Float64Var(&o.APICallsPerSecond, "api-calls-per-second", 10, "Maximum number of Github API calls to make per second, set to 0 to disable rate limiting")
+	fs.IntVar(&o.MaxConcurrentAPIRequests, "max-concurrent-api-requests", 5, "Maximum number of Github API requests to make at the same time")
+	fs.DurationVar(&o.APITimeout, "api-timeout", 30*time.Second, "Maximum duration of each Github API request, so that a hung request can't block a sync forever. 0 means no limit")
+	fs.StringVar(&o.UserAgent, "user-agent", defaultUserAgent(), "User-Agent header sent with Github API requests, which helps Github Support identify the tool's traffic. An empty value uses the default")
+	fs.StringVar(&o.SrcCABundle, "src-ca-bundle", "", "Path to a PEM file of CA certificates to trust, in addition to the system CAs, when connecting to src-url, e.g. for a GHES server with an internal CA")
+	fs.StringVar(&o.TgtCABundle, "tgt-ca-bundle", "", "Path to a PEM file of CA certificates to trust, in addition to the system CAs, when connecting to tgt-url")
+	fs.BoolVar(&o.ValidateConnectivity, "validate-connectivity", true, "Check that the source and target hosts are reachable before starting")
+}
+
+func (o clientOptions) Validate() (errors []string) {
+	if o.APICallsPerSecond < 0 {
+		errors = append(errors, "api-calls-per-second: must not be negative")
+	}
+	if o.MaxConcurrentAPIRequests < 1 {
+		errors = append(errors, "max-concurrent-api-requests: must be at least 1")
+	}
+	if o.APITimeout < 0 {
+		errors = append(errors, "api-timeout: must not be negative")
+	}
+	for _, f := range []struct {
+		name string
+		path string
+	}{
+		{"src-ca-bundle", o.SrcCABundle},
+		{"tgt-ca-bundle", o.TgtCABundle},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			errors = append(errors, f.name+": "+err.Error())
+		}
+	}
+	return errors
+}
+
+// CheckConnectivity checks that the hosts of the URLs are reachable, if validate-connectivity is set. Empty URLs are
+// skipped.
+func (o clientOptions) CheckConnectivity(urls ...string) error {
+	if !o.ValidateConnectivity {
+		return nil
+	}
+	if err := checkConnectivity(urls...); err != nil {
+		return fmt.Errorf("connectivity check failed: %w", err)
+	}
+	return nil
+}
+
+// NewClientFactory returns a clientFactory configured by the options. The CA bundles are used for both API requests
+// and git operations.
+func (o clientOptions) NewClientFactory() (*clientFactory, error) {
+	f := &clientFactory{
+		limiter:      newRateLimiter(o.APICallsPerSecond),
+		apiSemaphore: make(chan struct{}, o.MaxConcurrentAPIRequests),
+		stats:        newAPIStats(),
+		apiTimeout:   o.APITimeout,
+		userAgent:    userAgentOrDefault(o.UserAgent),
+	}
+	if o.SrcCABundle != "" || o.TgtCABundle != "" {
+		transport, err := useCABundles(o.SrcCABundle, o.TgtCABundle)
+		if err != nil {
+			return nil, err
+		}
+		f.transport = transport
+	}
+	return f, nil
+}
+
+// clientFactory creates Github API clients that share rate limiting and API usage stats.
+type clientFactory struct {
+	limiter *rateLimiter
+	// apiSemaphore bounds the number of concurrent API requests, independently of the number of repos being copied.
+	apiSemaphore chan struct{}
+	stats        *apiStats
+	// apiTimeout is the maximum duration of each API request, including reading the response, 0 means no limit.
+	apiTimeout time.Duration
+	// userAgent replaces the User-Agent header set by go-github, if set.
+	userAgent string
+	// transport makes the requests, http.DefaultTransport is used if it's nil.
+	transport http.RoundTripper
+}
+
+func (f *clientFactory) NewHTTPClient() *http.Client {
+	var transport http.RoundTripper = http.DefaultTransport
+	if f.transport != nil {
+		transport = f.transport
+	}
+	if f.userAgent != "" {
+		transport = &userAgentTransport{
+			userAgent: f.userAgent,
+			next:      transport,
+		}
+	}
+	if f.stats != nil {
+		transport = &countingTransport{
+			stats: f.stats,
+			next:  transport,
+		}
+	}
+	if f.apiSemaphore != nil {
+		transport = &concurrencyLimitedTransport{
+			sem:  f.apiSemaphore,
+			next: transport,
+		}
+	}
+	return &http.Client{
+		Transport: &rateLimitedTransport{
+			limiter: f.limiter,
+			next:    transport,
+		},
+		Timeout: f.apiTimeout,
+	}
+}
+
+// userAgentTransport sets the User-Agent header of requests, so that Github Support can identify the tool's traffic.
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.next.RoundTrip(req)
+}
+
+func defaultUserAgent() string {
+	return "copy-github-to-github/" + version
+}
+
+// userAgentOrDefault returns the default user agent if ua is empty, e.g. because the Github Action input isn't set.
+func userAgentOrDefault(ua string) string {
+	if ua == "" {
+		return defaultUserAgent()
+	}
+	return ua
+}
+
+func (f *clientFactory) NewGitHubClient(u *url.URL, token string) (client *github.Client, err error) {
+	client = github.NewClient(f.NewHTTPClient())
+	if token != "" {
+		client = client.WithAuthToken(token)
+	}
+	// The host includes the port, if any.
+	host := strings.ToLower(u.Host)
+	if !strings.EqualFold(u.Hostname(), "github.com") {
+		client, err = client.WithEnterpriseURLs(u.Scheme+"://"+host, u.Scheme+"://"+host)
+		if err != nil {
+			return client, fmt.Errorf("failed to set enterprise domain: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// NewGitHubRepoClient returns a RepoClient for the Github server of rawURL.
+func (f *clientFactory) NewGitHubRepoClient(rawURL, token string) (*GitHubRepoClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	client, err := f.NewGitHubClient(u, token)
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubRepoClient{Client: client}, nil
+}
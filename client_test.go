@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClientOptionsValidate(t *testing.T) {
+	var o clientOptions
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-src-ca-bundle", filepath.Join(t.TempDir(), "missing.pem")}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	errors := o.Validate()
+	if len(errors) != 1 || !strings.HasPrefix(errors[0], "src-ca-bundle:") {
+		t.Errorf("expected a src-ca-bundle error, got %v", errors)
+	}
+}
+
+func TestClientOptionsNewClientFactoryUsesDefaultUserAgent(t *testing.T) {
+	var userAgent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+	}))
+	defer srv.Close()
+
+	clients, err := clientOptions{MaxConcurrentAPIRequests: 1}.NewClientFactory()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := clients.NewHTTPClient().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if userAgent != defaultUserAgent() {
+		t.Errorf("expected user agent %q, got %q", defaultUserAgent(), userAgent)
+	}
+}
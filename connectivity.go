@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const connectivityTimeout = 10 * time.Second
+
+// checkConnectivity dials each URL's host to fail fast with a clear error if it's unreachable.
+func checkConnectivity(urls ...string) error {
+	for _, rawURL := range urls {
+		if rawURL == "" {
+			continue
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return fmt.Errorf("failed to parse url %q: %w", rawURL, err)
+		}
+		port := u.Port()
+		if port == "" {
+			port = "443"
+			if u.Scheme == "http" {
+				port = "80"
+			}
+		}
+		addr := net.JoinHostPort(u.Hostname(), port)
+		conn, err := net.DialTimeout("tcp", addr, connectivityTimeout)
+		if err != nil {
+			return fmt.Errorf("cannot reach %s: %w", addr, err)
+		}
+		conn.Close()
+	}
+	return nil
+}
+
+// waitForHost polls the /meta API endpoint of the Github server until it responds with 200 OK, e.g. while a GHES
+// instance is booting. The delay between attempts starts at 2s, and doubles up to a minute. If transport is nil,
+// http.DefaultTransport is used.
+func waitForHost(ctx context.Context, transport http.RoundTripper, ghURL string, timeout time.Duration) error {
+	u, err := url.Parse(ghURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse url %q: %w", ghURL, err)
+	}
+	metaURL := u.Scheme + "://" + u.Host + "/api/v3/meta"
+	if strings.EqualFold(u.Hostname(), "github.com") {
+		metaURL = "https://api.github.com/meta"
+	}
+	client := &http.Client{Transport: transport, Timeout: connectivityTimeout}
+	deadline := time.Now().Add(timeout)
+	delay := 2 * time.Second
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, metaURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("%s was not available after %v: %w", u.Host, timeout, err)
+		}
+		fmt.Printf("Waiting %v for %s to be available: %v\n", delay, u.Host, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, time.Minute)
+	}
+}
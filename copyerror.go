@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// CopyError is returned by copy, so that callers can get the details of a failure without parsing the message.
+type CopyError struct {
+	SrcURL string
+	TgtURL string
+	// Phase is the step that failed: prepare, clone, rewrite, create, push or sync.
+	Phase string
+	Cause error
+}
+
+func (e *CopyError) Error() string {
+	return e.Cause.Error()
+}
+
+func (e *CopyError) Unwrap() error {
+	return e.Cause
+}
+
+// logCopyError logs the fields of a CopyError, e.g. as JSON when -log-format is json.
+func logCopyError(err error) {
+	var ce *CopyError
+	if !errors.As(err, &ce) {
+		return
+	}
+	slog.Error("Copy failed",
+		slog.String("src", ce.SrcURL),
+		slog.String("tgt", ce.TgtURL),
+		slog.String("phase", ce.Phase),
+		slog.String("error", ce.Cause.Error()),
+	)
+}
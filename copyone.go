@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+func copyOne(args []string) error {
+	fs := flag.NewFlagSet("copy-one", flag.ContinueOnError)
+	srcAccessTokenFlag := fs.String("src-token", "", "Personal access token for pulling from github.com")
+	srcURLFlag := fs.String("src-url", "", "URL of the source repo, e.g. https://github.com/org/repo")
+	tgtAccessTokenFlag := fs.String("tgt-token", "", "Personal access token for pushing to Github Enterprise")
+	tgtURLFlag := fs.String("tgt-url", "", "URL of the target repo, e.g. https://github.enterprise.com/org/repo, or https://dev.azure.com/org/project/_git/repo when tgt-type is azure-devops")
+	var opts copyOptions
+	opts.RegisterFlags(fs)
+	var logOpts logOptions
+	logOpts.RegisterFlags(fs)
+	var clientOpts clientOptions
+	clientOpts.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var errors []string
+	if *srcAccessTokenFlag == "" {
+		errors = append(errors, "Missing src-token flag")
+	}
+	if *srcURLFlag == "" {
+		errors = append(errors, "Missing src-url flag")
+	}
+	if *tgtAccessTokenFlag == "" {
+		errors = append(errors, "Missing tgt-token flag")
+	}
+	if *tgtURLFlag == "" {
+		errors = append(errors, "Missing tgt-url flag")
+	}
+	errors = append(errors, opts.Validate()...)
+	errors = append(errors, logOpts.Validate()...)
+	errors = append(errors, clientOpts.Validate()...)
+	if len(errors) > 0 {
+		return fmt.Errorf("invalid or missing params:\n -%s", strings.Join(errors, "\n -"))
+	}
+
+	logOpts.Configure()
+
+	if err := clientOpts.CheckConnectivity(*srcURLFlag, *tgtURLFlag); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer cancel()
+
+	clients, err := clientOpts.NewClientFactory()
+	if err != nil {
+		return err
+	}
+	// Fail fast, in the same way as a sync.
+	if err = checkTokenScopes(ctx, clients, *srcURLFlag, *srcAccessTokenFlag, "src-token", "repo"); err != nil {
+		return err
+	}
+	if opts.TgtType == "github" {
+		if opts.TgtVersion, err = checkGitHubTarget(ctx, clients, *tgtURLFlag, *tgtAccessTokenFlag); err != nil {
+			return err
+		}
+	}
+
+	opts.PushThrottle = newPushThrottle(opts.MinPushInterval)
+
+	fmt.Printf("Copying %q to %q...\n", *srcURLFlag, *tgtURLFlag)
+	_, err = copy(ctx, clients, *srcAccessTokenFlag, *srcURLFlag, *tgtAccessTokenFlag, *tgtURLFlag, opts)
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// syncDeployKeys creates the source repo's public deploy keys on the target. Private keys can't be exported from
+// Github, so only the public keys are copied, and the holders of the private keys can use them with the target.
+// Keys with titles that mention the source host are assumed to be specific to the source, and are skipped.
+func syncDeployKeys(ctx context.Context, src, tgt repoRef) error {
+	srcKeys, err := listDeployKeys(ctx, src)
+	if err != nil {
+		return fmt.Errorf("failed to list source deploy keys: %w", err)
+	}
+	tgtKeys, err := listDeployKeys(ctx, tgt)
+	if err != nil {
+		return fmt.Errorf("failed to list target deploy keys: %w", err)
+	}
+	existing := make(map[string]struct{}, len(tgtKeys))
+	for _, k := range tgtKeys {
+		existing[normalizeKey(k.GetKey())] = struct{}{}
+	}
+	for _, k := range srcKeys {
+		if strings.Contains(strings.ToLower(k.GetTitle()), strings.ToLower(src.Host)) {
+			fmt.Printf("Skipping deploy key %q, because it's specific to %s.\n", k.GetTitle(), src.Host)
+			continue
+		}
+		if _, ok := existing[normalizeKey(k.GetKey())]; ok {
+			continue
+		}
+		_, _, err = tgt.Client.Repositories.CreateKey(ctx, tgt.Owner, tgt.Name, &github.Key{
+			Title:    k.Title,
+			Key:      k.Key,
+			ReadOnly: k.ReadOnly,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create deploy key %q: %w", k.GetTitle(), err)
+		}
+	}
+	return nil
+}
+
+func listDeployKeys(ctx context.Context, r repoRef) (keys []*github.Key, err error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := r.Client.Repositories.ListKeys(ctx, r.Owner, r.Name, opts)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, page...)
+		if resp.NextPage == 0 {
+			return keys, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// normalizeKey removes the comment from an SSH public key.
+func normalizeKey(key string) string {
+	fields := strings.Fields(key)
+	if len(fields) > 2 {
+		fields = fields[:2]
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// mirrorDescriptionPrefix starts the description of every target repo created by the tool, so that they can be told
+// apart from other repos in the target org.
+const mirrorDescriptionPrefix = "Mirror of "
+
+// mirrorDescription returns the description of a new target repo.
+func mirrorDescription(src, suffix string) string {
+	return withDescriptionSuffix(mirrorDescriptionPrefix+src, suffix)
+}
+
+// withDescriptionSuffix appends the suffix to a description, e.g. "[MIRROR]". A suffix that's already present is
+// removed first, so that it isn't added again by each sync.
+func withDescriptionSuffix(description, suffix string) string {
+	if suffix == "" {
+		return description
+	}
+	description = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(description), suffix))
+	if description == "" {
+		return suffix
+	}
+	return description + " " + suffix
+}
+
+// addDescriptionSuffix adds the suffix to the description of an existing target repo, if it doesn't already have it.
+func addDescriptionSuffix(ctx context.Context, rc RepoClient, owner string, tgtRepo Repo, suffix string) error {
+	description := withDescriptionSuffix(tgtRepo.Description, suffix)
+	if description == tgtRepo.Description {
+		return nil
+	}
+	if err := rc.EditRepo(ctx, owner, tgtRepo.Name, Repo{Description: description}); err != nil {
+		return fmt.Errorf("failed to update target description: %w", err)
+	}
+	return nil
+}
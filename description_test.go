@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestWithDescriptionSuffix(t *testing.T) {
+	tests := []struct {
+		description, suffix, want string
+	}{
+		{description: "Mirror of x", suffix: "", want: "Mirror of x"},
+		{description: "Mirror of x", suffix: "[MIRROR]", want: "Mirror of x [MIRROR]"},
+		{description: "Mirror of x [MIRROR]", suffix: "[MIRROR]", want: "Mirror of x [MIRROR]"},
+		{description: "", suffix: "[MIRROR]", want: "[MIRROR]"},
+	}
+	for _, tt := range tests {
+		if got := withDescriptionSuffix(tt.description, tt.suffix); got != tt.want {
+			t.Errorf("withDescriptionSuffix(%q, %q): expected %q, got %q", tt.description, tt.suffix, tt.want, got)
+		}
+	}
+}
+
+func TestAddDescriptionSuffix(t *testing.T) {
+	rc := newMockRepoClient("tgt",
+		Repo{Name: "old", Description: "Mirror of https://github.com/src/old"},
+		Repo{Name: "new", Description: "Mirror of https://github.com/src/new [MIRROR]"},
+	)
+	for _, name := range []string{"old", "new"} {
+		if err := addDescriptionSuffix(context.Background(), rc, "tgt", rc.Repos["tgt/"+name], "[MIRROR]"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if want := []string{"tgt/old"}; !slices.Equal(rc.Edited, want) {
+		t.Errorf("expected only %v to be edited, got %v", want, rc.Edited)
+	}
+	if got, want := rc.Repos["tgt/old"].Description, "Mirror of https://github.com/src/old [MIRROR]"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCreateTargetRepo(t *testing.T) {
+	opts := copyOptions{TgtVisibility: "internal", MirrorDescriptionSuffix: "[MIRROR]"}
+
+	t.Run("created", func(t *testing.T) {
+		rc := newMockRepoClient("tgt")
+		created, err := createTargetRepo(context.Background(), rc, "tgt", "repo", "https://github.com/src/repo", opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !created {
+			t.Error("expected the repo to be created")
+		}
+		want := Repo{Name: "repo", Description: "Mirror of https://github.com/src/repo [MIRROR]", Visibility: "internal"}
+		if got := rc.Repos["tgt/repo"]; got.Name != want.Name || got.Description != want.Description || got.Visibility != want.Visibility {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	})
+	t.Run("already exists", func(t *testing.T) {
+		rc := newMockRepoClient("tgt", Repo{Name: "repo"})
+		created, err := createTargetRepo(context.Background(), rc, "tgt", "repo", "https://github.com/src/repo", opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if created {
+			t.Error("expected an existing repo not to be reported as created")
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		rc := newMockRepoClient("tgt")
+		rc.CreateErr = errors.New("forbidden")
+		if _, err := createTargetRepo(context.Background(), rc, "tgt", "repo", "https://github.com/src/repo", opts); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
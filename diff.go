@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+)
+
+type refDiff struct {
+	Repo string `json:"repo"`
+	Ref  string `json:"ref,omitempty"`
+	// Status is "missing", "different", "extra", or "error" if the refs couldn't be listed.
+	Status string `json:"status"`
+	Src    string `json:"src,omitempty"`
+	Tgt    string `json:"tgt,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// diff prints the refs that differ between each source repo and its mirror, without pushing anything.
+func diff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	var opts mirrorCheckOptions
+	opts.RegisterFlags(fs)
+	formatFlag := fs.String("format", "table", "Output format, can be table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	errors := opts.Validate()
+	if msg := isOneOf(*formatFlag, "table", "json"); msg != "" {
+		errors = append(errors, "format: "+msg)
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("invalid or missing params:\n -%s", strings.Join(errors, "\n -"))
+	}
+
+	if err := opts.Clients.CheckConnectivity(opts.SrcURL, opts.TgtURL); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer cancel()
+
+	clients, err := opts.Clients.NewClientFactory()
+	if err != nil {
+		return err
+	}
+	repos, err := opts.ListRepos(ctx, clients)
+	if err != nil {
+		return err
+	}
+
+	diffs := []refDiff{}
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		tgt, err := opts.RepoMap.TargetURL(repo, opts.TgtURL)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite URL: %w", err)
+		}
+		discrepancies, err := verifyMirror(ctx, repo.URL, opts.SrcAccessToken, tgt, opts.TgtAccessToken)
+		if err != nil {
+			diffs = append(diffs, refDiff{Repo: repo.Name, Status: "error", Error: err.Error()})
+			continue
+		}
+		for _, d := range discrepancies {
+			rd := refDiff{Repo: repo.Name, Ref: d.Ref, Status: d.Problem}
+			if !d.Src.IsZero() {
+				rd.Src = d.Src.String()
+			}
+			if !d.Tgt.IsZero() {
+				rd.Tgt = d.Tgt.String()
+			}
+			diffs = append(diffs, rd)
+		}
+	}
+
+	if *formatFlag == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(diffs); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tREF\tSTATUS\tSOURCE\tTARGET")
+		for _, d := range diffs {
+			if d.Status == "error" {
+				fmt.Fprintf(w, "%s\t\t%s\t%s\t\n", d.Repo, d.Status, d.Error)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Repo, d.Ref, d.Status, d.Src, d.Tgt)
+		}
+		if err = w.Flush(); err != nil {
+			return err
+		}
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("found %d differences", len(diffs))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type smtpConfig struct {
+	Host          string
+	Port          int
+	User          string
+	Password      string
+	From          string
+	TLSSkipVerify bool
+}
+
+// sendEmailReport sends a plain text sync summary to the comma separated list of addresses. STARTTLS is used if the
+// server supports it.
+func sendEmailReport(ctx context.Context, cfg smtpConfig, to string, summary SyncResult) error {
+	recipients := strings.Split(to, ",")
+	for i := range recipients {
+		recipients[i] = strings.TrimSpace(recipients[i])
+	}
+	from := cfg.From
+	if from == "" {
+		from = cfg.User
+	}
+
+	subject := fmt.Sprintf("copy-github-to-github synced %d of %d repos", summary.Copied, summary.Total)
+	if summary.HasFailures() {
+		subject += fmt.Sprintf(", %d failed", len(summary.Failed))
+	}
+	body := new(strings.Builder)
+	fmt.Fprintf(body, "Total: %d\r\n", summary.Total)
+	fmt.Fprintf(body, "Copied: %d\r\n", summary.Copied)
+	fmt.Fprintf(body, "Failed: %d\r\n", len(summary.Failed))
+	// Repos that weren't attempted, e.g. because the sync was cancelled.
+	fmt.Fprintf(body, "Skipped: %d\r\n", summary.Total-summary.Copied-len(summary.Failed))
+	fmt.Fprintf(body, "Duration: %v\r\n", summary.Duration.Round(time.Second))
+	if summary.HasFailures() {
+		fmt.Fprintf(body, "\r\nFailed repos:\r\n")
+		for _, f := range summary.Failed {
+			fmt.Fprintf(body, "  %s (%s): %v\r\n", f.Name, f.TgtURL, f.Err)
+		}
+	}
+	msg := new(strings.Builder)
+	fmt.Fprintf(msg, "From: %s\r\n", from)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(msg, "\r\n%s", body.String())
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create SMTP client: %w", err)
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: cfg.Host, InsecureSkipVerify: cfg.TLSSkipVerify}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if cfg.User != "" {
+		if err = c.Auth(smtp.PlainAuth("", cfg.User, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate to SMTP server: %w", err)
+		}
+	}
+	if err = c.Mail(from); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, r := range recipients {
+		if err = c.Rcpt(r); err != nil {
+			return fmt.Errorf("failed to add recipient %q: %w", r, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err = w.Write([]byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return c.Quit()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// etaSmoothing is the weight given to the most recent repo in the moving average. Recent repos are weighted more
+// heavily, because network conditions change over a multi-hour run.
+const etaSmoothing = 0.2
+
+// etaEstimator estimates the time remaining in a sync from an exponential moving average of repo copy durations.
+type etaEstimator struct {
+	total       int
+	concurrency int
+	completed   int
+	avg         time.Duration
+}
+
+func newETAEstimator(total, concurrency int) *etaEstimator {
+	return &etaEstimator{
+		total:       total,
+		concurrency: concurrency,
+	}
+}
+
+// Add records the duration of a completed repo.
+func (e *etaEstimator) Add(d time.Duration) {
+	e.completed++
+	if e.completed == 1 {
+		e.avg = d
+		return
+	}
+	e.avg = time.Duration(etaSmoothing*float64(d) + (1-etaSmoothing)*float64(e.avg))
+}
+
+// Remaining returns the estimated time to copy the remaining repos, taking into account that repos are copied
+// concurrently.
+func (e *etaEstimator) Remaining() time.Duration {
+	remaining := e.total - e.completed
+	if remaining <= 0 {
+		return 0
+	}
+	workers := e.concurrency
+	if workers > remaining {
+		workers = remaining
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return time.Duration(remaining) * e.avg / time.Duration(workers)
+}
+
+// String returns the progress counter, e.g. "[47/312] (~4h23m remaining)".
+func (e *etaEstimator) String() string {
+	if e.completed >= e.total {
+		return fmt.Sprintf("[%d/%d]", e.completed, e.total)
+	}
+	return fmt.Sprintf("[%d/%d] (~%v remaining)", e.completed, e.total, formatETA(e.Remaining()))
+}
+
+// formatETA rounds the duration to make it readable, e.g. 4h23m rather than 4h23m12.345s.
+func formatETA(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	d = d.Round(time.Minute)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	if h == 0 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh%02dm", h, m)
+}
@@ -17,13 +17,18 @@
         inherit system;
         pkgs = import nixpkgs { inherit system; };
       });
+      # Flakes don't have access to git tags, so the version is a Go style pseudo-version, e.g. v0.0.0-20240115-abc1234.
+      commit = self.shortRev or "dirty";
+      date = "${builtins.substring 0 4 self.lastModifiedDate}-${builtins.substring 4 2 self.lastModifiedDate}-${builtins.substring 6 2 self.lastModifiedDate}";
+      version = "v0.0.0-${builtins.substring 0 8 self.lastModifiedDate}-${commit}";
     in
     {
       packages = forAllSystems ({ pkgs, ... }: rec {
         default = copy-github-to-github;
 
         copy-github-to-github = pkgs.buildGo121Module {
-          name = "copy-github-to-github";
+          pname = "copy-github-to-github";
+          inherit version;
           src = ./.;
           vendorHash = null;
           CGO_ENABLED = 0;
@@ -34,6 +39,9 @@
             "-s"
             "-w"
             "-extldflags -static"
+            "-X main.version=${version}"
+            "-X main.commit=${commit}"
+            "-X main.date=${date}"
           ];
         };
       });
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// multiFlag is a flag that can be passed multiple times. Each value can also be a newline separated list, since a
+// Github Action input can only pass the flag once, and blank values, e.g. from an unset input, are ignored.
+type multiFlag []string
+
+func (f *multiFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *multiFlag) Set(v string) error {
+	for _, line := range strings.Split(v, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			*f = append(*f, line)
+		}
+	}
+	return nil
+}
+
+func parseGitConfig(kv string) (section, subsection, key, value string, err error) {
+	name, value, ok := strings.Cut(kv, "=")
+	if !ok {
+		return section, subsection, key, value, fmt.Errorf("expected key=value, got %q", kv)
+	}
+	first := strings.Index(name, ".")
+	last := strings.LastIndex(name, ".")
+	if first <= 0 || last == len(name)-1 {
+		return section, subsection, key, value, fmt.Errorf("expected a key in the form section.key or section.subsection.key, got %q", name)
+	}
+	section, key = name[:first], name[last+1:]
+	if first != last {
+		subsection = name[first+1 : last]
+	}
+	return section, subsection, key, value, nil
+}
+
+// setGitConfig sets key=value pairs (e.g. http.sslCAInfo=/etc/ssl/ca.pem) in the repo's local config.
+func setGitConfig(repo *git.Repository, values []string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	for _, kv := range values {
+		section, subsection, key, value, err := parseGitConfig(kv)
+		if err != nil {
+			return err
+		}
+		if subsection != "" {
+			cfg.Raw.Section(section).Subsection(subsection).SetOption(key, value)
+			continue
+		}
+		cfg.Raw.Section(section).SetOption(key, value)
+	}
+	if err = repo.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// setAlternates adds a local object store, e.g. /srv/git-cache/objects, to the alternates of the repo in dir, before
+// it's cloned into. Objects that are in the store are read from it instead of being downloaded, and the clone doesn't
+// fetch at all when the store already has every branch and tag.
+func setAlternates(dir, objectsDir string) error {
+	abs, err := filepath.Abs(objectsDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path of %q: %w", objectsDir, err)
+	}
+	info := filepath.Join(dir, ".git", "objects", "info")
+	if err = os.MkdirAll(info, 0o755); err != nil {
+		return fmt.Errorf("failed to create %q: %w", info, err)
+	}
+	if err = os.WriteFile(filepath.Join(info, "alternates"), []byte(abs+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write alternates: %w", err)
+	}
+	return nil
+}
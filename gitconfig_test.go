@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"slices"
+	"testing"
+)
+
+func TestMultiFlag(t *testing.T) {
+	var f multiFlag
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&f, "git-config", "")
+	// An unset Github Action input passes an empty value, and a set one can contain several lines.
+	err := fs.Parse([]string{"-git-config=", "-git-config", "a.b=1", "-git-config", "c.d=2\n\n  e.f=3\n"})
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if want := []string{"a.b=1", "c.d=2", "e.f=3"}; !slices.Equal(f, want) {
+		t.Errorf("expected %v, got %v", want, f)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrGitTimeout is returned when a single git network operation (clone, push, or listing refs) exceeds -git-timeout.
+var ErrGitTimeout = errors.New("git operation timed out")
+
+// withGitTimeout runs a git network operation, cancelling it if it takes longer than timeout. A timeout of zero
+// disables the limit.
+func withGitTimeout(ctx context.Context, timeout time.Duration, op func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return op(ctx)
+	}
+	opCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := op(opCtx)
+	if err != nil && errors.Is(opCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return fmt.Errorf("%w after %v: %w", ErrGitTimeout, timeout, err)
+	}
+	return err
+}
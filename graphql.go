@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const listReposQuery = `query($login: String!, $after: String) {
+  repositoryOwner(login: $login) {
+    repositories(first: 100, after: $after, ownerAffiliations: [OWNER], orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+      nodes {
+        name
+        url
+        description
+        defaultBranchRef {
+          name
+        }
+        diskUsage
+        primaryLanguage {
+          name
+        }
+        createdAt
+        updatedAt
+        isFork
+        isArchived
+        hasWikiEnabled
+        watchers {
+          totalCount
+        }
+        repositoryTopics(first: 100) {
+          nodes {
+            topic {
+              name
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type listReposResponse struct {
+	Data struct {
+		RepositoryOwner *struct {
+			Repositories struct {
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []struct {
+					Name             string `json:"name"`
+					URL              string `json:"url"`
+					Description      string `json:"description"`
+					DefaultBranchRef *struct {
+						Name string `json:"name"`
+					} `json:"defaultBranchRef"`
+					DiskUsage       int `json:"diskUsage"`
+					PrimaryLanguage *struct {
+						Name string `json:"name"`
+					} `json:"primaryLanguage"`
+					CreatedAt      time.Time `json:"createdAt"`
+					UpdatedAt      time.Time `json:"updatedAt"`
+					IsFork         bool      `json:"isFork"`
+					IsArchived     bool      `json:"isArchived"`
+					HasWikiEnabled bool      `json:"hasWikiEnabled"`
+					Watchers       struct {
+						TotalCount int `json:"totalCount"`
+					} `json:"watchers"`
+					RepositoryTopics struct {
+						Nodes []struct {
+							Topic struct {
+								Name string `json:"name"`
+							} `json:"topic"`
+						} `json:"nodes"`
+					} `json:"repositoryTopics"`
+				} `json:"nodes"`
+			} `json:"repositories"`
+		} `json:"repositoryOwner"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// graphQLURL returns the GraphQL endpoint of a Github server.
+func graphQLURL(u *url.URL) string {
+	if strings.EqualFold(u.Hostname(), "github.com") {
+		return "https://api.github.com/graphql"
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host) + "/api/graphql"
+}
+
+// listReposGraphQL lists the repos of an org or user using the GraphQL API, which returns everything needed in one
+// request per 100 repos, and doesn't need to look up the type of the owner.
+func listReposGraphQL(ctx context.Context, clients *clientFactory, srcURL, token string, syncWikis bool) (repos []Repo, err error) {
+	ghURL, err := url.Parse(srcURL)
+	if err != nil {
+		return repos, fmt.Errorf("failed to parse url: %w", err)
+	}
+	httpClient := clients.NewHTTPClient()
+	owner := strings.Split(strings.Trim(ghURL.Path, "/"), "/")[0]
+	variables := map[string]any{"login": owner}
+	for {
+		body, err := json.Marshal(map[string]any{
+			"query":     listReposQuery,
+			"variables": variables,
+		})
+		if err != nil {
+			return repos, fmt.Errorf("failed to marshal GraphQL query: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, graphQLURL(ghURL), bytes.NewReader(body))
+		if err != nil {
+			return repos, fmt.Errorf("failed to create GraphQL request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return repos, fmt.Errorf("failed to list repos: %w", err)
+		}
+		var page listReposResponse
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return repos, fmt.Errorf("failed to list repos: unexpected status %s", resp.Status)
+		}
+		if err != nil {
+			return repos, fmt.Errorf("failed to decode GraphQL response: %w", err)
+		}
+		if len(page.Errors) > 0 {
+			return repos, fmt.Errorf("failed to list repos: %s", page.Errors[0].Message)
+		}
+		if page.Data.RepositoryOwner == nil {
+			return repos, fmt.Errorf("owner %q not found", owner)
+		}
+		for _, n := range page.Data.RepositoryOwner.Repositories.Nodes {
+			expected := ghURL.Scheme + "://" + ghURL.Host + "/" + owner + "/" + n.Name
+			if !strings.EqualFold(n.URL, expected) {
+				fmt.Printf("Notice: repo %q has been transferred to %q, update your configuration to use the new URL.\n", expected, n.URL)
+			}
+			repo := Repo{
+				Name:        n.Name,
+				URL:         n.URL,
+				Description: n.Description,
+				Size:        n.DiskUsage,
+				CreatedAt:   n.CreatedAt,
+				UpdatedAt:   n.UpdatedAt,
+				Fork:        n.IsFork,
+				Archived:    n.IsArchived,
+				HasWiki:     n.HasWikiEnabled,
+				Watchers:    n.Watchers.TotalCount,
+			}
+			if n.DefaultBranchRef != nil {
+				repo.DefaultBranch = n.DefaultBranchRef.Name
+			}
+			if n.PrimaryLanguage != nil {
+				repo.Language = n.PrimaryLanguage.Name
+			}
+			for _, t := range n.RepositoryTopics.Nodes {
+				repo.Topics = append(repo.Topics, t.Topic.Name)
+			}
+			repos = append(repos, repo)
+			if syncWikis && n.HasWikiEnabled && n.Watchers.TotalCount > 0 {
+				repos = append(repos, wikiRepo(repo))
+			}
+		}
+		pageInfo := page.Data.RepositoryOwner.Repositories.PageInfo
+		if !pageInfo.HasNextPage {
+			return repos, nil
+		}
+		variables["after"] = pageInfo.EndCursor
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListReposGraphQL(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		var body struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		query = body.Query
+		w.Write([]byte(`{"data":{"repositoryOwner":{"repositories":{"pageInfo":{"hasNextPage":false},"nodes":[
+			{"name":"repo","url":"` + "http://" + r.Host + `/owner/repo","description":"A repo","defaultBranchRef":{"name":"trunk"},"hasWikiEnabled":true,"watchers":{"totalCount":1}},
+			{"name":"empty","url":"` + "http://" + r.Host + `/owner/empty","defaultBranchRef":null}
+		]}}}}`))
+	}))
+	defer srv.Close()
+
+	repos, err := listReposGraphQL(context.Background(), new(clientFactory), srv.URL+"/owner", "token", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(query, "ownerAffiliations: [OWNER]") {
+		t.Errorf("expected the query to only list repos owned by the owner, got %s", query)
+	}
+	if len(repos) != 2 {
+		t.Fatalf("expected 2 repos, got %+v", repos)
+	}
+	if repos[0].Description != "A repo" || repos[0].DefaultBranch != "trunk" || !repos[0].HasWiki || repos[0].Watchers != 1 {
+		t.Errorf("unexpected repo: %+v", repos[0])
+	}
+	if repos[1].DefaultBranch != "" {
+		t.Errorf("expected no default branch for an empty repo, got %q", repos[1].DefaultBranch)
+	}
+}
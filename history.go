@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// historyTruncator removes commits made before a date from a repo. Commits whose parents are all removed become new
+// root commits. Since git commits are snapshots of the whole tree, the content of the retained commits is unchanged,
+// but their SHAs change. If every commit of the checked out branch was made before the date, its latest commit is kept,
+// so that there's still something to copy.
+type historyTruncator struct {
+	repo   *git.Repository
+	before time.Time
+	// head is the checked out branch.
+	head plumbing.ReferenceName
+	// rewritten maps original commit hashes to new ones. Removed commits map to the zero hash.
+	rewritten map[plumbing.Hash]plumbing.Hash
+}
+
+func truncateHistory(repo *git.Repository, before time.Time) error {
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	t := &historyTruncator{
+		repo:      repo,
+		before:    before,
+		head:      head.Target(),
+		rewritten: make(map[plumbing.Hash]plumbing.Hash),
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return fmt.Errorf("failed to list refs: %w", err)
+	}
+	defer refs.Close()
+	return refs.ForEach(t.rewriteRef)
+}
+
+func (t *historyTruncator) rewriteRef(ref *plumbing.Reference) error {
+	if ref.Type() != plumbing.HashReference {
+		return nil
+	}
+	newHash, err := t.rewriteObject(ref.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", ref.Name(), err)
+	}
+	if newHash == ref.Hash() {
+		return nil
+	}
+	if newHash.IsZero() && ref.Name() == t.head {
+		fmt.Printf("Warning: every commit of %s was made before the strip-history-before date, so only its latest commit is kept.\n", ref.Name().Short())
+		if newHash, err = t.keepTip(ref.Hash()); err != nil {
+			return fmt.Errorf("failed to rewrite %s: %w", ref.Name(), err)
+		}
+	}
+	if newHash.IsZero() {
+		return t.repo.Storer.RemoveReference(ref.Name())
+	}
+	return t.repo.Storer.SetReference(plumbing.NewHashReference(ref.Name(), newHash))
+}
+
+// rewriteObject rewrites a commit, or an annotated tag that points to a commit.
+func (t *historyTruncator) rewriteObject(h plumbing.Hash) (plumbing.Hash, error) {
+	tag, err := t.repo.TagObject(h)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return t.rewriteCommit(h)
+	}
+	if err != nil {
+		return h, err
+	}
+	if tag.TargetType != plumbing.CommitObject {
+		return h, nil
+	}
+	target, err := t.rewriteCommit(tag.Target)
+	if err != nil || target == tag.Target {
+		return h, err
+	}
+	if target.IsZero() {
+		return target, nil
+	}
+	tag.Target = target
+	// The signature is no longer valid for the rewritten tag.
+	tag.PGPSignature = ""
+	return t.store(tag)
+}
+
+// rewriteCommit rewrites a commit and its ancestors. Histories can be hundreds of thousands of commits deep, so
+// they're walked with an explicit stack rather than recursion, and each commit is rewritten once its parents have been.
+func (t *historyTruncator) rewriteCommit(h plumbing.Hash) (plumbing.Hash, error) {
+	pending := make(map[plumbing.Hash]*object.Commit)
+	stack := []plumbing.Hash{h}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		if _, ok := t.rewritten[current]; ok {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		commit, ok := pending[current]
+		if !ok {
+			var err error
+			commit, err = t.repo.CommitObject(current)
+			if errors.Is(err, plumbing.ErrObjectNotFound) {
+				// The parents of the oldest commits in a shallow clone aren't present.
+				t.rewritten[current] = plumbing.ZeroHash
+				continue
+			}
+			if err != nil {
+				return h, err
+			}
+			if commit.Committer.When.Before(t.before) {
+				t.rewritten[current] = plumbing.ZeroHash
+				continue
+			}
+			pending[current] = commit
+		}
+		// Rewrite the parents first.
+		var waiting bool
+		for _, p := range commit.ParentHashes {
+			if _, ok := t.rewritten[p]; !ok {
+				stack = append(stack, p)
+				waiting = true
+			}
+		}
+		if waiting {
+			continue
+		}
+		stack = stack[:len(stack)-1]
+		delete(pending, current)
+		newHash, err := t.rewriteParents(commit)
+		if err != nil {
+			return h, err
+		}
+		t.rewritten[current] = newHash
+	}
+	return t.rewritten[h], nil
+}
+
+// keepTip rewrites a commit as a root commit, with the same tree.
+func (t *historyTruncator) keepTip(h plumbing.Hash) (plumbing.Hash, error) {
+	commit, err := t.repo.CommitObject(h)
+	if err != nil {
+		return h, err
+	}
+	commit.ParentHashes = nil
+	// The signature is no longer valid for the rewritten commit.
+	commit.PGPSignature = ""
+	return t.store(commit)
+}
+
+// rewriteParents replaces the parents of a commit with their rewritten hashes, once they have all been rewritten.
+func (t *historyTruncator) rewriteParents(commit *object.Commit) (plumbing.Hash, error) {
+	var parents []plumbing.Hash
+	for _, p := range commit.ParentHashes {
+		if newParent := t.rewritten[p]; !newParent.IsZero() {
+			parents = append(parents, newParent)
+		}
+	}
+	if equalHashes(parents, commit.ParentHashes) {
+		return commit.Hash, nil
+	}
+	commit.ParentHashes = parents
+	// The signature is no longer valid for the rewritten commit.
+	commit.PGPSignature = ""
+	return t.store(commit)
+}
+
+func (t *historyTruncator) store(o interface {
+	Encode(plumbing.EncodedObject) error
+}) (plumbing.Hash, error) {
+	obj := t.repo.Storer.NewEncodedObject()
+	if err := o.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return t.repo.Storer.SetEncodedObject(obj)
+}
+
+func equalHashes(a, b []plumbing.Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
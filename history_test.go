@@ -0,0 +1,192 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// storeTestCommit stores a commit of an empty tree, without a worktree, so that long histories can be created quickly.
+func storeTestCommit(t *testing.T, repo *git.Repository, msg string, when time.Time, parents ...plumbing.Hash) plumbing.Hash {
+	t.Helper()
+	treeObj := repo.Storer.NewEncodedObject()
+	if err := (&object.Tree{}).Encode(treeObj); err != nil {
+		t.Fatalf("failed to encode tree: %v", err)
+	}
+	tree, err := repo.Storer.SetEncodedObject(treeObj)
+	if err != nil {
+		t.Fatalf("failed to store tree: %v", err)
+	}
+	sig := object.Signature{Name: "Test", Email: "test@example.com", When: when}
+	commit := &object.Commit{
+		Author:       sig,
+		Committer:    sig,
+		Message:      msg,
+		TreeHash:     tree,
+		ParentHashes: parents,
+	}
+	obj := repo.Storer.NewEncodedObject()
+	if err = commit.Encode(obj); err != nil {
+		t.Fatalf("failed to encode commit: %v", err)
+	}
+	h, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatalf("failed to store commit: %v", err)
+	}
+	return h
+}
+
+func countCommits(t *testing.T, repo *git.Repository, h plumbing.Hash) (count, roots int) {
+	t.Helper()
+	commit, err := repo.CommitObject(h)
+	if err != nil {
+		t.Fatalf("failed to get commit: %v", err)
+	}
+	iter := object.NewCommitPreorderIter(commit, nil, nil)
+	err = iter.ForEach(func(c *object.Commit) error {
+		count++
+		if c.NumParents() == 0 {
+			roots++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk commits: %v", err)
+	}
+	return count, roots
+}
+
+func TestTruncateHistoryLongHistory(t *testing.T) {
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	const total = 50000
+	var head plumbing.Hash
+	for i := 0; i < total; i++ {
+		var parents []plumbing.Hash
+		if !head.IsZero() {
+			parents = append(parents, head)
+		}
+		head = storeTestCommit(t, repo, "commit", start.Add(time.Duration(i)*time.Minute), parents...)
+	}
+	branch := plumbing.NewBranchReferenceName("main")
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(branch, head)); err != nil {
+		t.Fatalf("failed to set branch: %v", err)
+	}
+
+	const kept = 100
+	if err = truncateHistory(repo, start.Add((total-kept)*time.Minute)); err != nil {
+		t.Fatalf("failed to truncate history: %v", err)
+	}
+	ref, err := repo.Reference(branch, true)
+	if err != nil {
+		t.Fatalf("failed to get branch: %v", err)
+	}
+	if ref.Hash() == head {
+		t.Fatal("expected the branch to be rewritten")
+	}
+	count, roots := countCommits(t, repo, ref.Hash())
+	if count != kept {
+		t.Errorf("expected %d commits, got %d", kept, count)
+	}
+	if roots != 1 {
+		t.Errorf("expected 1 root commit, got %d", roots)
+	}
+}
+
+func TestTruncateHistoryMerges(t *testing.T) {
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	// old <- main1 <- merge
+	//    \<- feature1 <-/
+	old := storeTestCommit(t, repo, "old", day(1))
+	main1 := storeTestCommit(t, repo, "main1", day(3), old)
+	feature1 := storeTestCommit(t, repo, "feature1", day(4), old)
+	merge := storeTestCommit(t, repo, "merge", day(5), main1, feature1)
+	// A branch that only has old commits is removed.
+	stale := storeTestCommit(t, repo, "stale", day(1))
+	for name, h := range map[string]plumbing.Hash{"main": merge, "feature": feature1, "stale": stale} {
+		if err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), h)); err != nil {
+			t.Fatalf("failed to set branch: %v", err)
+		}
+	}
+
+	if err = truncateHistory(repo, day(2)); err != nil {
+		t.Fatalf("failed to truncate history: %v", err)
+	}
+
+	if _, err = repo.Reference(plumbing.NewBranchReferenceName("stale"), true); err == nil {
+		t.Error("expected the stale branch to be removed")
+	}
+	mainRef, err := repo.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatalf("failed to get main: %v", err)
+	}
+	count, roots := countCommits(t, repo, mainRef.Hash())
+	if count != 3 || roots != 2 {
+		t.Errorf("expected 3 commits with 2 roots, got %d commits with %d roots", count, roots)
+	}
+	// The feature branch and the merge's second parent are the same rewritten commit.
+	featureRef, err := repo.Reference(plumbing.NewBranchReferenceName("feature"), true)
+	if err != nil {
+		t.Fatalf("failed to get feature: %v", err)
+	}
+	mergeCommit, err := repo.CommitObject(mainRef.Hash())
+	if err != nil {
+		t.Fatalf("failed to get merge commit: %v", err)
+	}
+	if mergeCommit.NumParents() != 2 || mergeCommit.ParentHashes[1] != featureRef.Hash() {
+		t.Errorf("expected the merge's second parent to be %s, got %v", featureRef.Hash(), mergeCommit.ParentHashes)
+	}
+}
+
+func TestTruncateHistoryKeepsTipOfHeadWhenEveryCommitIsOld(t *testing.T) {
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	first := storeTestCommit(t, repo, "first", day(1))
+	second := storeTestCommit(t, repo, "second", day(2), first)
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	for name, h := range map[plumbing.ReferenceName]plumbing.Hash{head.Target(): second, "refs/heads/other": second} {
+		if err = repo.Storer.SetReference(plumbing.NewHashReference(name, h)); err != nil {
+			t.Fatalf("failed to set branch: %v", err)
+		}
+	}
+
+	if err = truncateHistory(repo, day(3)); err != nil {
+		t.Fatalf("failed to truncate history: %v", err)
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		t.Fatalf("expected HEAD to resolve: %v", err)
+	}
+	count, roots := countCommits(t, repo, ref.Hash())
+	if count != 1 || roots != 1 {
+		t.Errorf("expected a single root commit, got %d commits with %d roots", count, roots)
+	}
+	tip, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatalf("failed to get commit: %v", err)
+	}
+	if tip.Message != "second" {
+		t.Errorf("expected the latest commit to be kept, got %q", tip.Message)
+	}
+	if _, err = repo.Reference("refs/heads/other", true); err == nil {
+		t.Error("expected the other branch to be removed")
+	}
+}
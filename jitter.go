@@ -0,0 +1,45 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseJitter parses a jitter value, which is either a duration (e.g. 30s), or a percentage of every (e.g. 10%).
+func parseJitter(v string, every time.Duration) (jitter time.Duration, err error) {
+	if pc, ok := strings.CutSuffix(v, "%"); ok {
+		f, err := strconv.ParseFloat(pc, 64)
+		if err != nil {
+			return jitter, fmt.Errorf("invalid percentage %q: %w", v, err)
+		}
+		if f < 0 {
+			return jitter, fmt.Errorf("percentage %q must not be negative", v)
+		}
+		return time.Duration(float64(every) * f / 100), nil
+	}
+	jitter, err = time.ParseDuration(v)
+	if err != nil {
+		return jitter, fmt.Errorf("invalid duration %q: %w", v, err)
+	}
+	if jitter < 0 {
+		return jitter, fmt.Errorf("duration %q must not be negative", v)
+	}
+	return jitter, nil
+}
+
+// randomJitter returns a random duration in the range [0, max]. It uses crypto/rand, so that instances started
+// at the same time don't share the same sequence.
+func randomJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)+1))
+	if err != nil {
+		return 0
+	}
+	return time.Duration(n.Int64())
+}
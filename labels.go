@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// syncLabels creates or updates the source repo's issue labels on the target. If deleteRemoved is set, target labels
+// that aren't in the source are deleted.
+func syncLabels(ctx context.Context, src, tgt repoRef, deleteRemoved bool) error {
+	srcLabels, err := listLabels(ctx, src)
+	if err != nil {
+		return fmt.Errorf("failed to list source labels: %w", err)
+	}
+	tgtLabels, err := listLabels(ctx, tgt)
+	if err != nil {
+		return fmt.Errorf("failed to list target labels: %w", err)
+	}
+	// Label names are case-insensitive.
+	existing := make(map[string]*github.Label, len(tgtLabels))
+	for _, l := range tgtLabels {
+		existing[strings.ToLower(l.GetName())] = l
+	}
+	for _, l := range srcLabels {
+		label := &github.Label{
+			Name:        l.Name,
+			Color:       l.Color,
+			Description: l.Description,
+		}
+		key := strings.ToLower(l.GetName())
+		t, ok := existing[key]
+		delete(existing, key)
+		if !ok {
+			if _, _, err = tgt.Client.Issues.CreateLabel(ctx, tgt.Owner, tgt.Name, label); err != nil {
+				return fmt.Errorf("failed to create label %q: %w", l.GetName(), err)
+			}
+			continue
+		}
+		if t.GetName() == l.GetName() && t.GetColor() == l.GetColor() && t.GetDescription() == l.GetDescription() {
+			continue
+		}
+		if _, _, err = tgt.Client.Issues.EditLabel(ctx, tgt.Owner, tgt.Name, t.GetName(), label); err != nil {
+			return fmt.Errorf("failed to update label %q: %w", l.GetName(), err)
+		}
+	}
+	if !deleteRemoved {
+		return nil
+	}
+	for _, t := range existing {
+		if _, err = tgt.Client.Issues.DeleteLabel(ctx, tgt.Owner, tgt.Name, t.GetName()); err != nil {
+			return fmt.Errorf("failed to delete label %q: %w", t.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func listLabels(ctx context.Context, r repoRef) (labels []*github.Label, err error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := r.Client.Issues.ListLabels(ctx, r.Owner, r.Name, opts)
+		if err != nil {
+			return labels, err
+		}
+		labels = append(labels, page...)
+		if resp.NextPage == 0 {
+			return labels, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
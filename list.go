@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+func list(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	srcAccessTokenFlag := fs.String("src-token", "", "Personal access token for reading from the source")
+	srcURLFlag := fs.String("src-url", "", "URL of source organization or repo, e.g. https://github.com/org")
+	srcTypeFlag := fs.String("src-type", "github", "Type of the source, can be github or bitbucket")
+	srcOrgTypeFlag := fs.String("src-org-type", "auto", "Type of the src-url owner, can be auto, org or user")
+	formatFlag := fs.String("format", "table", "Output format, can be table or json. The json output can be used as a -src-repo-list-file")
+	var filter repoFilter
+	filter.RegisterFlags(fs)
+	var clientOpts clientOptions
+	clientOpts.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var errors []string
+	if *srcAccessTokenFlag == "" {
+		errors = append(errors, "Missing src-token flag")
+	}
+	if *srcURLFlag == "" {
+		errors = append(errors, "Missing src-url flag")
+	}
+	if msg := isOneOf(*srcTypeFlag, "github", "bitbucket"); msg != "" {
+		errors = append(errors, "src-type: "+msg)
+	}
+	if msg := isOneOf(*srcOrgTypeFlag, "auto", "org", "user"); msg != "" {
+		errors = append(errors, "src-org-type: "+msg)
+	}
+	if msg := isOneOf(*formatFlag, "table", "json"); msg != "" {
+		errors = append(errors, "format: "+msg)
+	}
+	errors = append(errors, filter.Validate()...)
+	errors = append(errors, clientOpts.Validate()...)
+	if filter.RequireTopic != "" && (*srcTypeFlag != "github" || !isOrgURL(*srcURLFlag)) {
+		errors = append(errors, "src-require-topic: only supported when src-url is a Github organization or user")
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("invalid or missing params:\n -%s", strings.Join(errors, "\n -"))
+	}
+
+	if err := clientOpts.CheckConnectivity(*srcURLFlag); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer cancel()
+
+	clients, err := clientOpts.NewClientFactory()
+	if err != nil {
+		return err
+	}
+	var repos []Repo
+	if *srcTypeFlag == "bitbucket" {
+		repos, err = listBitbucketRepos(ctx, clients, *srcURLFlag, *srcAccessTokenFlag)
+	} else {
+		repos, err = listRepos(ctx, clients, *srcURLFlag, *srcAccessTokenFlag, *srcOrgTypeFlag, false)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list repos: %w", err)
+	}
+	repos = filter.Apply(repos)
+
+	if *formatFlag == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(repos)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tURL\tSIZE (KB)\tLANGUAGE\tUPDATED\tFORK\tARCHIVED")
+	for _, r := range repos {
+		var updated string
+		if !r.UpdatedAt.IsZero() {
+			updated = r.UpdatedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%v\t%v\n", r.Name, r.URL, r.Size, r.Language, updated, r.Fork, r.Archived)
+	}
+	return w.Flush()
+}
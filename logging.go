@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type logOptions struct {
+	Level  string
+	Format string
+}
+
+func (o *logOptions) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Level, "log-level", "info", "Minimum level of structured log messages, can be debug, info, warn or error. Git clone and push progress is logged at debug")
+	fs.StringVar(&o.Format, "log-format", "text", "Format of structured log messages, can be text or json")
+}
+
+func (o logOptions) Validate() (errors []string) {
+	if msg := isOneOf(o.Level, "debug", "info", "warn", "error"); msg != "" {
+		errors = append(errors, "log-level: "+msg)
+	}
+	if msg := isOneOf(o.Format, "text", "json"); msg != "" {
+		errors = append(errors, "log-format: "+msg)
+	}
+	return errors
+}
+
+// Configure sets the default slog logger.
+func (o logOptions) Configure() {
+	var level slog.Level
+	level.UnmarshalText([]byte(o.Level))
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(os.Stdout, opts)
+	if o.Format == "json" {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+var gitProgressPercent = regexp.MustCompile(`(\d+)%`)
+
+// gitProgressWriter parses the progress messages sent by git servers, e.g. "Receiving objects:  45% (123/270)", and
+// logs them at debug level. Messages are separated by \r as they're updated, and by \n when a phase is done.
+type gitProgressWriter struct {
+	repo    string
+	mutex   sync.Mutex
+	partial string
+	// lastPercent is used to only log when the percentage changes, by phase.
+	lastPercent map[string]int
+	stats       transferStats
+}
+
+func newGitProgressWriter(repo string) *gitProgressWriter {
+	return &gitProgressWriter{
+		repo:        repo,
+		lastPercent: make(map[string]int),
+	}
+}
+
+func (w *gitProgressWriter) Write(p []byte) (n int, err error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	lines := strings.FieldsFunc(w.partial+string(p), func(r rune) bool { return r == '\r' || r == '\n' })
+	w.partial = ""
+	if len(p) > 0 && p[len(p)-1] != '\r' && p[len(p)-1] != '\n' && len(lines) > 0 {
+		w.partial = lines[len(lines)-1]
+		lines = lines[:len(lines)-1]
+	}
+	for _, line := range lines {
+		w.log(line)
+	}
+	return len(p), nil
+}
+
+// Stats returns the size of the transfer reported so far.
+func (w *gitProgressWriter) Stats() transferStats {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.stats
+}
+
+func (w *gitProgressWriter) log(line string) {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "remote:"))
+	w.stats.parse(line)
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok {
+		slog.Debug(line, slog.String("repo", w.repo))
+		return
+	}
+	// The phase is the first word, e.g. "counting" in "Counting objects".
+	phase := strings.ToLower(strings.Fields(name + " ")[0])
+	done := strings.HasSuffix(rest, "done.")
+	percent := -1
+	if m := gitProgressPercent.FindStringSubmatch(rest); m != nil {
+		percent, _ = strconv.Atoi(m[1])
+	}
+	if last, ok := w.lastPercent[phase]; ok && last == percent && !done {
+		return
+	}
+	w.lastPercent[phase] = percent
+	attrs := []any{slog.String("repo", w.repo), slog.String("phase", phase)}
+	if percent >= 0 {
+		attrs = append(attrs, slog.Int("percent", percent))
+	}
+	slog.Debug(line, attrs...)
+}
@@ -5,10 +5,15 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/url"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -16,6 +21,9 @@ import (
 	"flag"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/google/go-github/v55/github"
 )
@@ -27,66 +35,112 @@ var usage string
 var unit string
 
 func main() {
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		if err := annotateOutput(); err != nil {
+			fmt.Printf("Warning: failed to annotate output for Github Actions: %v\n", err)
+		}
+		defer flushOutput()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "self-update" {
+		if err := selfUpdate(os.Args[2:]); err != nil {
+			fmt.Printf("Failed to self-update: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "copy-one" {
+		if err := copyOne(os.Args[2:]); err != nil {
+			fmt.Printf("Failed to copy: %v\n", err)
+			logCopyError(err)
+			exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate-to-mirror" {
+		if err := migrateToMirror(os.Args[2:]); err != nil {
+			fmt.Printf("Failed to migrate: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "list" {
+		if err := list(os.Args[2:]); err != nil {
+			fmt.Printf("Failed to list: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		if err := verify(os.Args[2:]); err != nil {
+			fmt.Printf("Failed to verify: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		if err := diff(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to diff: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
 	fs := flag.NewFlagSet("global", flag.ContinueOnError)
-	srcAccessTokenFlag := fs.String("src-token", "", "Personal access token for pulling from github.com")
-	srcURLFlag := fs.String("src-url", "", "URL of source organization or repo, e.g. https://github.com/org")
-	tgtAccessTokenFlag := fs.String("tgt-token", "", "Personal access token for pushing to Github Enterprise")
-	tgtURLFlag := fs.String("tgt-url", "", "URL of target org to push to, e.g. https://github.enterprise.com/org")
-	tgtVisibilityFlag := fs.String("tgt-visibility", "public", "Set the visibility of new repos created, can be public, internal or private")
-	everyFlag := fs.Duration("every", time.Duration(0), "If set, keep running, and sync again after a delay.")
+	var cfg Config
+	cfg.RegisterFlags(fs)
 	printSystemdUnitFlag := fs.Bool("print-systemd-unit", false, "Set to true to output the systemd unit file instead of running the program")
 	helpFlag := fs.Bool("help", false, "Show help.")
+	versionFlag := fs.Bool("version", false, "Print the version and exit")
 	fs.Parse(os.Args[1:])
 	if *helpFlag {
 		fmt.Print(usage)
 		fs.PrintDefaults()
-		os.Exit(0)
-	}
-
-	var errors []string
-	if *srcAccessTokenFlag == "" {
-		errors = append(errors, "Missing src-token flag")
-	}
-	if *srcURLFlag == "" {
-		errors = append(errors, "Missing src-url flag")
+		exit(0)
 	}
-	if *tgtAccessTokenFlag == "" {
-		errors = append(errors, "Missing tgt-token flag")
+	if *versionFlag {
+		fmt.Println(versionString())
+		exit(0)
 	}
-	if *tgtURLFlag == "" {
-		errors = append(errors, "Missing tgt-url flag")
-	}
-	if msg := isOneOf(*tgtVisibilityFlag, "public", "internal", "private"); msg != "" {
-		errors = append(errors, "tgt-visibility: "+msg)
-	}
-	if len(errors) > 0 {
+
+	if errors := cfg.Validate(); len(errors) > 0 {
 		fmt.Println("Invalid or missing params:")
 		fmt.Println("\n -" + strings.Join(errors, "\n -"))
-		os.Exit(1)
+		exit(1)
 	}
 
 	if *printSystemdUnitFlag {
 		cmd := new(strings.Builder)
 		cmd.WriteString("/usr/local/bin/copy-github-to-github")
-		cmd.WriteString(" -src-token ")
-		cmd.WriteString(*srcAccessTokenFlag)
-		cmd.WriteString(" -src-url ")
-		cmd.WriteString(*srcURLFlag)
-		cmd.WriteString(" -tgt-token ")
-		cmd.WriteString(*tgtAccessTokenFlag)
-		cmd.WriteString(" -tgt-url ")
-		cmd.WriteString(*tgtURLFlag)
-		cmd.WriteString(" -tgt-visibility ")
-		cmd.WriteString(*tgtVisibilityFlag)
-		if *everyFlag > time.Duration(0) {
-			cmd.WriteString(" -every ")
-			cmd.WriteString((*everyFlag).String())
-		}
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "print-systemd-unit" {
+				return
+			}
+			if mf, ok := f.Value.(*multiFlag); ok {
+				for _, v := range *mf {
+					cmd.WriteString(" -" + f.Name + " " + v)
+				}
+				return
+			}
+			if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+				cmd.WriteString(" -" + f.Name + "=" + f.Value.String())
+				return
+			}
+			cmd.WriteString(" -" + f.Name + " " + f.Value.String())
+		})
+		unit = strings.Replace(unit, "$VERSION", versionString(), -1)
 		unit = strings.Replace(unit, "$CMD", cmd.String(), -1)
 		fmt.Println(unit)
 		return
 	}
 
+	cfg.Log.Configure()
+	buildCommit, buildDate := buildInfo()
+	slog.Info("Starting", "version", version, "commit", buildCommit, "built", buildDate)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT)
@@ -95,41 +149,13 @@ func main() {
 		cancel()
 	}()
 
-loop:
-	for {
-		fmt.Printf("Listing repos for URL: %v\n", *srcURLFlag)
-		repos, err := listRepos(ctx, *srcURLFlag, *srcAccessTokenFlag)
-		if err != nil {
-			fmt.Printf("Failed to list repos: %v\n", err)
-			os.Exit(1)
-		}
-
-		fmt.Printf("Copying %d repos.\n", len(repos))
-
-		for _, repo := range repos {
-			tgt, err := rewriteURL(repo, *tgtURLFlag)
-			if err != nil {
-				fmt.Printf("Failed to rewrite URL %q: %v\n", repo.URL, err)
-				os.Exit(1)
-			}
-			fmt.Printf("Copying %q to %q...\n", repo.URL, tgt)
-			if err = copy(ctx, *srcAccessTokenFlag, repo.URL, *tgtAccessTokenFlag, tgt, *tgtVisibilityFlag); err != nil {
-				fmt.Printf("Failed to copy: %v\n", err)
-				os.Exit(1)
-			}
-		}
-
-		if *everyFlag == time.Duration(0) {
-			break loop
-		}
-		fmt.Printf("Process complete. Running again in %v.\n", *everyFlag)
-		select {
-		case <-ctx.Done():
-			fmt.Printf("Context closed.\n")
-			break loop
-		case <-time.After(*everyFlag):
-			fmt.Printf("Wait complete.\n")
-		}
+	result, err := run(ctx, cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		exit(1)
+	}
+	if result.HasFailures() {
+		exit(1)
 	}
 }
 
@@ -166,18 +192,67 @@ func rewriteURL(r Repo, tgt string) (updated string, err error) {
 }
 
 type Repo struct {
-	Name string
-	URL  string
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Size is in kilobytes.
+	Size          int       `json:"size,omitempty"`
+	Language      string    `json:"language,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt,omitempty"`
+	CreatedAt     time.Time `json:"createdAt,omitempty"`
+	Fork          bool      `json:"fork,omitempty"`
+	Archived      bool      `json:"archived,omitempty"`
+	Description   string    `json:"description,omitempty"`
+	Visibility    string    `json:"visibility,omitempty"`
+	DefaultBranch string    `json:"defaultBranch,omitempty"`
+	Topics        []string  `json:"topics,omitempty"`
+	// HasWiki and Watchers are only set when listing Github repos, and are used to decide whether to copy the wiki.
+	HasWiki  bool `json:"-"`
+	Watchers int  `json:"-"`
 }
 
-func listRepos(ctx context.Context, ghURL, token string) (repos []Repo, err error) {
+func isOrgURL(ghURL string) bool {
+	u, err := url.Parse(ghURL)
+	if err != nil {
+		return false
+	}
+	return len(strings.Split(strings.Trim(u.Path, "/"), "/")) == 1
+}
+
+// sameHostAndOrg returns true if both URLs are for the same org on the same host. Hosts and orgs are compared
+// case-insensitively, since Github treats them that way.
+func sameHostAndOrg(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	orgA := strings.Split(strings.Trim(ua.Path, "/"), "/")[0]
+	orgB := strings.Split(strings.Trim(ub.Path, "/"), "/")[0]
+	return strings.EqualFold(normaliseHost(ua), normaliseHost(ub)) && strings.EqualFold(orgA, orgB)
+}
+
+// normaliseHost returns the hostname, dropping the default port of the scheme and the www prefix.
+func normaliseHost(u *url.URL) string {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	port := u.Port()
+	if port == "" || (port == "443" && u.Scheme == "https") || (port == "80" && u.Scheme == "http") {
+		return host
+	}
+	return host + ":" + port
+}
+
+// listRepos lists the repos at ghURL. If syncWikis is set, the wikis of the repos of an org or user are also listed.
+func listRepos(ctx context.Context, clients *clientFactory, ghURL, token, ownerType string, syncWikis bool) (repos []Repo, err error) {
 	u, err := url.Parse(ghURL)
 	if err != nil {
 		return repos, fmt.Errorf("failed to parse url: %w", err)
 	}
 	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
 	if len(segments) == 1 {
-		return listReposForOrg(ctx, u, token)
+		return listReposForOwner(ctx, clients, u, token, ownerType, syncWikis)
 	}
 	if len(segments) > 2 {
 		return repos, fmt.Errorf("unexpected number of path segments in URL, expected /<org> or /<org>/<repo>, got %q", ghURL)
@@ -189,107 +264,774 @@ func listRepos(ctx context.Context, ghURL, token string) (repos []Repo, err erro
 	return repos, nil
 }
 
-func listReposForOrg(ctx context.Context, ghURL *url.URL, token string) (repos []Repo, err error) {
+// listReposForOwner lists the repos of an org or user. If ownerType is auto, the type of the owner is looked up.
+func listReposForOwner(ctx context.Context, clients *clientFactory, ghURL *url.URL, token, ownerType string, syncWikis bool) (repos []Repo, err error) {
 	// Create the client.
-	host := strings.ToLower(ghURL.Hostname())
-	client := github.NewClient(nil).WithAuthToken(token)
-	if host != "github.com" {
-		client, err = client.WithEnterpriseURLs(ghURL.Scheme+"://"+host, ghURL.Scheme+"://"+host)
-		if err != nil {
-			return repos, fmt.Errorf("failed to set enterprise domain: %w", err)
-		}
+	client, err := clients.NewGitHubClient(ghURL, token)
+	if err != nil {
+		return repos, err
 	}
 	// Get the org name.
 	org := strings.Split(strings.Trim(ghURL.Path, "/"), "/")[0]
 
-	var pageIndex int
-	for {
-		r, _, err := client.Repositories.ListByOrg(ctx, org, &github.RepositoryListByOrgOptions{
-			Sort:      "updated",
-			Direction: "desc",
-			ListOptions: github.ListOptions{
-				Page:    pageIndex,
-				PerPage: 100,
-			},
-		})
+	if ownerType == "auto" {
+		user, _, err := client.Users.Get(ctx, org)
 		if err != nil {
-			return repos, fmt.Errorf("failed to list repos: %w", err)
+			return repos, fmt.Errorf("failed to get owner type: %w", err)
 		}
-		if len(r) == 0 {
-			break
+		ownerType = "user"
+		if user.GetType() == "Organization" {
+			ownerType = "org"
 		}
-		for _, rr := range r {
-			repos = append(repos, Repo{
-				Name: rr.GetName(),
-				URL:  rr.GetHTMLURL(),
-			})
+	}
+
+	var rc RepoClient = &GitHubRepoClient{Client: client}
+	r, err := rc.ListRepos(ctx, org, ownerType)
+	if err != nil {
+		return repos, fmt.Errorf("failed to list repos: %w", err)
+	}
+	for _, repo := range r {
+		expected := ghURL.Scheme + "://" + ghURL.Host + "/" + org + "/" + repo.Name
+		if !strings.EqualFold(repo.URL, expected) {
+			fmt.Printf("Notice: repo %q has been transferred to %q, update your configuration to use the new URL.\n", expected, repo.URL)
+		}
+		repos = append(repos, repo)
+		// The API doesn't say whether a wiki has any pages, so only repos that have watchers are assumed to have one.
+		if syncWikis && repo.HasWiki && repo.Watchers > 0 {
+			repos = append(repos, wikiRepo(repo))
 		}
-		pageIndex++
 	}
 	return repos, nil
 }
 
-func copy(ctx context.Context, srcAccessToken, src, tgtAccessToken, tgt, tgtVisibility string) error {
-	// Clone to local.
-	dir, err := os.MkdirTemp(os.TempDir(), "src_repo_")
+// listGitHubRepos lists the repos of an org or user, most recently updated first. ownerType is org or user.
+func listGitHubRepos(ctx context.Context, client *github.Client, owner, ownerType string) (repos []*github.Repository, err error) {
+	listOptions := github.ListOptions{
+		Page:    1,
+		PerPage: 100,
+	}
+	for {
+		var r []*github.Repository
+		var resp *github.Response
+		if ownerType == "user" {
+			r, resp, err = client.Repositories.List(ctx, owner, &github.RepositoryListOptions{
+				Sort:        "updated",
+				Direction:   "desc",
+				ListOptions: listOptions,
+			})
+		} else {
+			r, resp, err = client.Repositories.ListByOrg(ctx, owner, &github.RepositoryListByOrgOptions{
+				Sort:        "updated",
+				Direction:   "desc",
+				ListOptions: listOptions,
+			})
+		}
+		if err != nil {
+			return repos, err
+		}
+		repos = append(repos, r...)
+		if resp.NextPage == 0 {
+			return repos, nil
+		}
+		listOptions.Page = resp.NextPage
+	}
+}
+
+type copyOptions struct {
+	TgtType             string
+	TgtVisibility       string
+	CommitMessageFilter string
+	// NewRepoDepth is the clone depth used when the target repo doesn't exist yet, 0 means a full clone.
+	NewRepoDepth      int
+	TgtTeam           string
+	TgtTeamPermission string
+	StripCIConfigs    bool
+	ForcePush         bool
+	GitConfig         multiFlag
+	// GitAlternates is the path of a local object store that is used as an alternate of the clone.
+	GitAlternates string
+	GitTimeout    time.Duration
+	NoProgress    bool
+	// MinPushInterval is the minimum time between pushes, across all of the repos being copied concurrently.
+	MinPushInterval time.Duration
+	// MigrationWaitTimeout is the maximum time to wait for a GHES repo import to finish before creating or pushing.
+	MigrationWaitTimeout time.Duration
+	// RenameDefaultBranch is in the form old=new.
+	RenameDefaultBranch string
+	ChecksumVerify      bool
+	SyncDeployKeys      bool
+	LabelSync           bool
+	LabelSyncDelete     bool
+	SyncRulesets        bool
+	SyncSecurity        bool
+	TgtInitGitignore    string
+	ChecksumAlgo        string
+	TgtDeleteOnFailure  bool
+	TgtRunnerGroup      string
+	// MirrorDescriptionSuffix is appended to the descriptions of target repos, e.g. [MIRROR].
+	MirrorDescriptionSuffix string
+	// StripHistoryBefore is an RFC 3339 date, commits made before it are not copied.
+	StripHistoryBefore string
+	// TgtSquashHistory replaces the history of the default branch with a single commit, and only pushes that branch.
+	TgtSquashHistory bool
+	// ProtectedBranches is a comma separated list of branch names or globs that are never force-pushed.
+	ProtectedBranches string
+	// PersistentWorkDir holds a clone of each repo that is reused by the next sync, instead of a temp directory.
+	PersistentWorkDir string
+	// TgtVersion is detected at startup, and used to skip features the target doesn't support.
+	TgtVersion serverVersion
+	// DontCreate is set for repos that have been copied before, so that a missing target isn't recreated.
+	DontCreate bool
+	// PushThrottle is created from MinPushInterval at startup, and shared by every copy.
+	PushThrottle *pushThrottle
+}
+
+func (o *copyOptions) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.TgtType, "tgt-type", "github", "Type of the target, can be github or azure-devops. When azure-devops, tgt-url is the URL of an Azure DevOps project, e.g. https://dev.azure.com/org/project, and tgt-token is a personal access token with the Code (Read & write) scope")
+	fs.StringVar(&o.TgtVisibility, "tgt-visibility", "public", "Set the visibility of new repos created, can be public, internal or private")
+	fs.StringVar(&o.CommitMessageFilter, "commit-message-filter", "", "Path to a git filter-repo message callback script used to redact commit messages before pushing. Warning: this rewrites history and changes commit SHAs, so divergent history will be force-pushed to the target")
+	fs.IntVar(&o.NewRepoDepth, "new-repo-depth", 0, "Clone depth to use when creating a new target repo. Only 0, which clones the full history, is supported, because go-git can't push from a shallow clone")
+	fs.StringVar(&o.TgtTeam, "tgt-team", "", "Slug of a team in the target org to grant access to newly created repos")
+	fs.StringVar(&o.TgtTeamPermission, "tgt-team-permission", "push", "Permission to grant tgt-team on newly created repos, can be pull, push, admin, maintain or triage")
+	fs.BoolVar(&o.ForcePush, "force-push", true, "Set to false to fail instead of overwriting target branches that have diverged from the source")
+	fs.StringVar(&o.ProtectedBranches, "protected-branches", "", "Comma separated list of branch names or globs, e.g. main,release/*, that are never force-pushed. Target branches that match and have diverged from the source are left unchanged, with a warning")
+	fs.StringVar(&o.GitAlternates, "git-alternates", "", "Path of a local git object store, e.g. /srv/git-cache/objects, to use as an alternate of each clone. Objects that are already in the store aren't downloaded again")
+	fs.Var(&o.GitConfig, "git-config", "A key=value pair to set in the git config of the local clone before pushing, e.g. http.sslCAInfo=/etc/ssl/ca.pem. Can be passed multiple times, or as a newline separated list")
+	fs.StringVar(&o.RenameDefaultBranch, "rename-default-branch", "", "Rename the default branch on the target when the source default branch has the old name, in the form old=new, e.g. master=main")
+	fs.BoolVar(&o.ChecksumVerify, "checksum-verify", false, "Set to true to check that the commit SHA of each branch on the target matches the pushed commit")
+	fs.DurationVar(&o.MigrationWaitTimeout, "migration-wait-timeout", 5*time.Minute, "Maximum time to wait, with exponential back-off, for a target repo that is being migrated before creating or pushing to it")
+	fs.BoolVar(&o.NoProgress, "no-progress", false, "Set to true to not request progress messages from git servers during clones and pushes, which reduces log volume when logging at debug level. The size of pushes is then not reported")
+	fs.DurationVar(&o.MinPushInterval, "min-push-interval", 0, "Minimum time between pushes to the target, regardless of concurrency, e.g. 10s. Clones aren't limited. 0 means no limit")
+	fs.DurationVar(&o.GitTimeout, "git-timeout", 0, "Maximum duration of each git network operation (clone, push or listing refs), 0 means no limit")
+	fs.BoolVar(&o.SyncDeployKeys, "sync-deploy-keys", false, "Set to true to copy the public deploy keys of Github source repos to the target")
+	fs.BoolVar(&o.LabelSync, "label-sync", false, "Set to true to copy the issue labels of Github source repos to the target")
+	fs.BoolVar(&o.LabelSyncDelete, "label-sync-delete", true, "When label-sync is set, delete target labels that aren't in the source. Set to false to keep them")
+	fs.BoolVar(&o.SyncRulesets, "sync-rulesets", false, "Set to true to copy the rulesets of Github source repos to the target. Targets older than GHES 3.11 get the branch protection rules instead")
+	fs.BoolVar(&o.SyncSecurity, "sync-security-settings", false, "Set to true to copy the Advanced Security, secret scanning, push protection and code scanning default setup settings of Github source repos to the target. Private and internal repos are skipped if the target org doesn't have a Github Advanced Security license")
+	fs.StringVar(&o.ChecksumAlgo, "checksum-algo", "sha1", "Hash algorithm of new target repos, can be sha1 or sha256. A warning is printed if the source uses a different algorithm, since objects can't be transferred between them")
+	fs.StringVar(&o.TgtInitGitignore, "tgt-init-gitignore", "", "Name of a Github gitignore template (e.g. Go) used to initialize the target when the source repo is empty. By default, empty source repos are skipped")
+	fs.StringVar(&o.MirrorDescriptionSuffix, "mirror-description-suffix", "", "Suffix to append to the descriptions of target repos, e.g. [MIRROR], so that mirrors can be identified. It's also added to existing target repos that don't have it")
+	fs.StringVar(&o.TgtRunnerGroup, "tgt-runner-group", "", "Name or ID of an Actions runner group in the target org to give newly created repos access to. The runner group must be restricted to selected repositories")
+	fs.BoolVar(&o.TgtDeleteOnFailure, "tgt-delete-on-failure", false, "Set to true to delete target repos created by the tool if the first push to them fails, instead of leaving them empty")
+	fs.StringVar(&o.StripHistoryBefore, "strip-history-before", "", "RFC 3339 date (e.g. 2020-01-01T00:00:00Z), commits made before it are removed from the history pushed to the target. Warning: this rewrites history and changes commit SHAs, the oldest remaining commits become root commits")
+	fs.StringVar(&o.PersistentWorkDir, "persistent-work-dir", "", "Directory to keep a clone of each repo in, e.g. a Docker volume. Clones are updated by fetching on later syncs, instead of cloning into a new temp directory each time")
+	fs.BoolVar(&o.TgtSquashHistory, "tgt-squash-history", false, "Set to true to push only the default branch, as a single commit containing its latest tree. Warning: this is lossy, the history, other branches and tags of the source are not copied")
+	fs.BoolVar(&o.StripCIConfigs, "strip-ci-configs", false, "Set to true to remove CI configuration (e.g. .github/workflows, .travis.yml, Jenkinsfile) from the default branch before pushing. Warning: this adds a commit to the tip of the branch, so its SHA on the target won't match the source")
+}
+
+// gitProgress returns the writer to pass to go-git, which is nil if progress messages aren't wanted. A nil
+// *gitProgressWriter can't be used, because it isn't a nil io.Writer.
+func (o copyOptions) gitProgress(w *gitProgressWriter) io.Writer {
+	if o.NoProgress {
+		return nil
+	}
+	return w
+}
+
+func (o copyOptions) Validate() (errors []string) {
+	if msg := isOneOf(o.TgtType, "github", "azure-devops"); msg != "" {
+		errors = append(errors, "tgt-type: "+msg)
+	}
+	if o.TgtType == "azure-devops" {
+		githubOnly := []struct {
+			name string
+			set  bool
+		}{
+			{"tgt-team", o.TgtTeam != ""},
+			{"tgt-runner-group", o.TgtRunnerGroup != ""},
+			{"tgt-init-gitignore", o.TgtInitGitignore != ""},
+			{"tgt-delete-on-failure", o.TgtDeleteOnFailure},
+			{"rename-default-branch", o.RenameDefaultBranch != ""},
+			{"checksum-verify", o.ChecksumVerify},
+			{"checksum-algo", o.ChecksumAlgo != "sha1"},
+			{"sync-deploy-keys", o.SyncDeployKeys},
+			{"label-sync", o.LabelSync},
+			{"sync-rulesets", o.SyncRulesets},
+			{"sync-security-settings", o.SyncSecurity},
+			{"mirror-description-suffix", o.MirrorDescriptionSuffix != ""},
+		}
+		for _, f := range githubOnly {
+			if f.set {
+				errors = append(errors, f.name+": only supported when tgt-type is github")
+			}
+		}
+	}
+	if msg := isOneOf(o.TgtVisibility, "public", "internal", "private"); msg != "" {
+		errors = append(errors, "tgt-visibility: "+msg)
+	}
+	if o.CommitMessageFilter != "" {
+		if _, err := os.Stat(o.CommitMessageFilter); err != nil {
+			errors = append(errors, "commit-message-filter: "+err.Error())
+		}
+	}
+	// go-git can't push from a shallow clone, since it doesn't have the parents of the oldest commits, which a new repo
+	// is missing, so only full clones are accepted until it can unshallow.
+	if o.NewRepoDepth != 0 {
+		errors = append(errors, "new-repo-depth: must be 0, because pushing from a shallow clone isn't supported")
+	}
+	if o.MinPushInterval < 0 {
+		errors = append(errors, "min-push-interval: must not be negative")
+	}
+	if o.GitAlternates != "" {
+		if fi, err := os.Stat(o.GitAlternates); err != nil {
+			errors = append(errors, "git-alternates: "+err.Error())
+		} else if !fi.IsDir() {
+			errors = append(errors, "git-alternates: must be a directory")
+		}
+	}
+	if _, err := parseProtectedBranches(o.ProtectedBranches); err != nil {
+		errors = append(errors, "protected-branches: "+err.Error())
+	}
+	for _, kv := range o.GitConfig {
+		if _, _, _, _, err := parseGitConfig(kv); err != nil {
+			errors = append(errors, "git-config: "+err.Error())
+		}
+	}
+	if o.RenameDefaultBranch != "" {
+		if _, _, err := parseBranchRename(o.RenameDefaultBranch); err != nil {
+			errors = append(errors, "rename-default-branch: "+err.Error())
+		}
+	}
+	if o.StripHistoryBefore != "" {
+		if _, err := time.Parse(time.RFC3339, o.StripHistoryBefore); err != nil {
+			errors = append(errors, "strip-history-before: "+err.Error())
+		}
+	}
+	if msg := isOneOf(o.ChecksumAlgo, "sha1", "sha256"); msg != "" {
+		errors = append(errors, "checksum-algo: "+msg)
+	}
+	if msg := isOneOf(o.TgtTeamPermission, "pull", "push", "admin", "maintain", "triage"); msg != "" {
+		errors = append(errors, "tgt-team-permission: "+msg)
+	}
+	return errors
+}
+
+// copyStatus describes the change made to the target by a copy.
+type copyStatus string
+
+const (
+	copyNew       copyStatus = "new"
+	copyUpdated   copyStatus = "updated"
+	copyUnchanged copyStatus = "unchanged"
+	// copyDeleted means the target was deleted after being copied, and wasn't recreated.
+	copyDeleted copyStatus = "deleted"
+)
+
+func copy(ctx context.Context, clients *clientFactory, srcAccessToken, src, tgtAccessToken, tgt string, opts copyOptions) (status copyStatus, err error) {
+	phase := "prepare"
+	defer func() {
+		if err != nil {
+			err = &CopyError{SrcURL: src, TgtURL: tgt, Phase: phase, Cause: err}
+		}
+	}()
+
+	// Get the enterprise domain.
+	u, err := url.Parse(tgt)
 	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+		return status, fmt.Errorf("failed to parse url: %w", err)
+	}
+	// client is only set when the target is Github, which supports more features than other targets.
+	var client *github.Client
+	var rc RepoClient
+	var owner, name string
+	if opts.TgtType == "azure-devops" {
+		if owner, name, err = parseAzureDevOpsRepoURL(u); err != nil {
+			return status, err
+		}
+		rc = newAzureDevOpsClient(clients, u, tgtAccessToken)
+	} else {
+		if client, err = clients.NewGitHubClient(u, tgtAccessToken); err != nil {
+			return status, err
+		}
+		owner, name = path.Split(u.Path)
+		owner = strings.Trim(owner, "/")
+		name = strings.Trim(name, "/")
+		rc = &GitHubRepoClient{Client: client, Version: opts.TgtVersion, ObjectFormat: opts.ChecksumAlgo}
+	}
+
+	// Wikis are pushed to the wiki of the target repo, which must be created first.
+	wiki := client != nil && isWikiName(name)
+	if wiki {
+		if err = enableWiki(ctx, client, owner, strings.TrimSuffix(name, wikiSuffix)); err != nil {
+			return status, err
+		}
+	}
+
+	// Check whether the target already exists.
+	tgtExists := true
+	var tgtRepo Repo
+	if !wiki {
+		if tgtRepo, tgtExists, err = rc.GetRepo(ctx, owner, name); err != nil {
+			return status, fmt.Errorf("failed to get target repo: %w", err)
+		}
+	}
+
+	if !tgtExists && opts.DontCreate {
+		fmt.Printf("Warning: skipping %q, because %q was deleted after being copied.\n", src, tgt)
+		return copyDeleted, nil
+	}
+
+	// Objects can't be transferred between repos that use different hash algorithms.
+	if !tgtExists {
+		srcFormat, err := detectObjectFormat(ctx, src, srcAccessToken)
+		if err != nil {
+			return status, err
+		}
+		if srcFormat != opts.ChecksumAlgo {
+			fmt.Printf("Warning: %q uses %s object hashes, but the target will be created with %s, so objects can't be transferred directly.\n", src, srcFormat, opts.ChecksumAlgo)
+		}
+	}
+
+	phase = "clone"
+	// Clone to local, or update the clone left in the persistent work dir by the last sync.
+	var dir string
+	var repo *git.Repository
+	if opts.PersistentWorkDir != "" {
+		if dir, err = persistentCloneDir(opts.PersistentWorkDir, src); err != nil {
+			return status, err
+		}
+		if _, err = os.Stat(dir); err == nil {
+			if repo, err = updateClone(ctx, dir, src, srcAccessToken, opts, name); err != nil {
+				fmt.Printf("Warning: failed to reuse the clone of %q in %q, cloning again: %v\n", src, dir, err)
+			}
+		}
+		if repo == nil {
+			if err = os.RemoveAll(dir); err != nil {
+				return status, fmt.Errorf("failed to remove %q: %w", dir, err)
+			}
+			if err = os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+				return status, fmt.Errorf("failed to create work directory: %w", err)
+			}
+		}
+	} else {
+		if dir, err = os.MkdirTemp(os.TempDir(), "src_repo_"); err != nil {
+			return status, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
 	}
-	defer os.RemoveAll(dir)
-	repo, err := git.PlainClone(dir, false, &git.CloneOptions{
+	cloneOptions := &git.CloneOptions{
 		URL: src,
 		Auth: &http.BasicAuth{
-			Username: "git",
+			Username: gitUsername(src),
 			Password: srcAccessToken,
 		},
-		Progress: os.Stdout,
-	})
+	}
+	// Updates always use a full clone, so that the history of existing mirrors isn't truncated.
+	if !tgtExists {
+		cloneOptions.Depth = opts.NewRepoDepth
+	}
+	if repo == nil && opts.GitAlternates != "" {
+		if err = setAlternates(dir, opts.GitAlternates); err != nil {
+			return status, err
+		}
+	}
+	if repo == nil {
+		progress := newGitProgressWriter(name)
+		cloneOptions.Progress = opts.gitProgress(progress)
+		start := time.Now()
+		err = withGitTimeout(ctx, opts.GitTimeout, func(ctx context.Context) (err error) {
+			repo, err = git.PlainCloneContext(ctx, dir, false, cloneOptions)
+			return err
+		})
+		if err == nil {
+			stats := progress.Stats()
+			if stats.Bytes == 0 {
+				stats.Bytes = packSize(dir)
+			}
+			logTransfer(name, "clone", stats, time.Since(start))
+		}
+	}
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		if opts.TgtInitGitignore == "" || tgtExists {
+			fmt.Printf("Warning: skipping %q, because it's empty.\n", src)
+			return copyUnchanged, nil
+		}
+		return copyNew, initEmptyTarget(ctx, client, owner, name, src, opts)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to clone: %w", err)
+		return status, fmt.Errorf("failed to clone: %w", err)
+	}
+	// The clone only has a local branch for the default branch, and every branch is pushed.
+	if err = trackRemoteBranches(repo); err != nil {
+		return status, err
+	}
+	if len(opts.GitConfig) > 0 {
+		if err = setGitConfig(repo, opts.GitConfig); err != nil {
+			return status, fmt.Errorf("failed to set git config: %w", err)
+		}
 	}
 
-	// Get the enterprise domain.
-	u, err := url.Parse(tgt)
+	phase = "rewrite"
+	// Rewrite commit messages.
+	if opts.CommitMessageFilter != "" {
+		if repo, err = filterCommitMessages(ctx, repo, dir, src, opts.CommitMessageFilter); err != nil {
+			return status, fmt.Errorf("failed to filter commit messages: %w", err)
+		}
+	}
+
+	// Remove old history.
+	if opts.StripHistoryBefore != "" {
+		before, err := time.Parse(time.RFC3339, opts.StripHistoryBefore)
+		if err != nil {
+			return status, fmt.Errorf("failed to parse strip-history-before: %w", err)
+		}
+		if err = truncateHistory(repo, before); err != nil {
+			return status, fmt.Errorf("failed to strip history: %w", err)
+		}
+	}
+
+	// Remove CI configuration.
+	if opts.StripCIConfigs {
+		stripped, err := stripCIConfigs(repo, dir)
+		if err != nil {
+			return status, fmt.Errorf("failed to strip CI configs: %w", err)
+		}
+		if stripped {
+			fmt.Printf("Removed CI configuration from %q.\n", src)
+		}
+	}
+
+	// Replace the history with a single commit.
+	if opts.TgtSquashHistory {
+		if err = squashHistory(repo); err != nil {
+			return status, fmt.Errorf("failed to squash history: %w", err)
+		}
+	}
+
+	phase = "create"
+	// Create the target.
+	var tgtCreated bool
+	if !tgtExists {
+		if tgtCreated, err = createTargetRepo(ctx, rc, owner, name, src, opts); err != nil {
+			return status, err
+		}
+		if opts.TgtRunnerGroup != "" {
+			created, _, err := client.Repositories.Get(ctx, owner, name)
+			if err != nil {
+				return status, fmt.Errorf("failed to get target repo: %w", err)
+			}
+			if err = addRepoToRunnerGroup(ctx, client, owner, opts.TgtRunnerGroup, created.GetID()); err != nil {
+				return status, err
+			}
+		}
+		if opts.TgtTeam != "" {
+			_, err = client.Teams.AddTeamRepoBySlug(ctx, owner, opts.TgtTeam, owner, name, &github.TeamAddTeamRepoOptions{
+				Permission: opts.TgtTeamPermission,
+			})
+			if err != nil {
+				return status, fmt.Errorf("failed to add team %q to target repo: %w", opts.TgtTeam, err)
+			}
+		}
+	}
+
+	// Add the suffix to the descriptions of repos created before it was set.
+	if tgtExists && !wiki && opts.MirrorDescriptionSuffix != "" {
+		if err = addDescriptionSuffix(ctx, rc, owner, tgtRepo, opts.MirrorDescriptionSuffix); err != nil {
+			return status, err
+		}
+	}
+
+	phase = "push"
+	// Rename the default branch.
+	if opts.RenameDefaultBranch != "" && !wiki {
+		from, to, _ := parseBranchRename(opts.RenameDefaultBranch)
+		renamed, err := renameLocalBranch(repo, from, to)
+		if err != nil {
+			return status, fmt.Errorf("failed to rename default branch: %w", err)
+		}
+		// Renaming the branch on the target, rather than pushing a new one, also updates its branch protection rules.
+		if renamed && tgtExists && tgtRepo.DefaultBranch == from {
+			if !opts.TgtVersion.Supports(featureRenameBranch) {
+				return status, fmt.Errorf("renaming the default branch requires %s, target is %v", requires(featureRenameBranch), opts.TgtVersion)
+			}
+			fmt.Printf("Renaming default branch of %q from %q to %q...\n", tgt, from, to)
+			if _, _, err = client.Repositories.RenameBranch(ctx, owner, name, from, to); err != nil {
+				return status, fmt.Errorf("failed to rename target default branch: %w", err)
+			}
+		}
+	}
+
+	// The target's refs are needed to check protected branches, and to log the branches changed by the push at debug
+	// level. Logging is best effort, so it doesn't fail the copy.
+	var refsBefore map[string]plumbing.Hash
+	logChanges := slog.Default().Enabled(ctx, slog.LevelDebug)
+	if opts.ProtectedBranches != "" || logChanges {
+		err = withGitTimeout(ctx, opts.GitTimeout, func(ctx context.Context) (err error) {
+			refsBefore, err = listRemoteRefs(ctx, tgt, tgtAccessToken)
+			return err
+		})
+		if err != nil && opts.ProtectedBranches != "" {
+			return status, err
+		}
+		if err != nil {
+			slog.Debug("Failed to list target refs, branch changes won't be logged", slog.String("repo", name), slog.String("error", err.Error()))
+			logChanges = false
+		}
+	}
+
+	// Push to target.
+	refSpecs, err := pushRefSpecs(repo, opts.TgtSquashHistory)
 	if err != nil {
-		return fmt.Errorf("failed to parse url: %w", err)
+		return status, err
+	}
+	if opts.ProtectedBranches != "" {
+		// The patterns are validated at startup.
+		patterns, _ := parseProtectedBranches(opts.ProtectedBranches)
+		var skipped []string
+		if refSpecs, skipped, err = excludeProtectedBranches(repo, refSpecs, refsBefore, patterns); err != nil {
+			return status, fmt.Errorf("failed to check protected branches: %w", err)
+		}
+		for _, branch := range skipped {
+			fmt.Printf("Warning: %q: not force-pushing protected branch %q, because it has diverged from the source\n", name, branch)
+		}
+	}
+	pushProgress := newGitProgressWriter(name)
+	pushStart := time.Now()
+	if len(refSpecs) == 0 {
+		// Every branch is protected and has diverged.
+		err = git.NoErrAlreadyUpToDate
+	} else {
+		err = retryWhileMigrating(ctx, opts.MigrationWaitTimeout, name, func() error {
+			if err := opts.PushThrottle.Wait(ctx); err != nil {
+				return err
+			}
+			return withGitTimeout(ctx, opts.GitTimeout, func(ctx context.Context) error {
+				return repo.PushContext(ctx, &git.PushOptions{
+					RemoteURL: tgt,
+					Auth: &http.BasicAuth{
+						Username: "git",
+						Password: tgtAccessToken,
+					},
+					RefSpecs: refSpecs,
+					Force:    opts.ForcePush,
+					Progress: opts.gitProgress(pushProgress),
+				})
+			})
+		})
+	}
+	if err == nil {
+		logTransfer(name, "push", pushProgress.Stats(), time.Since(pushStart))
+	}
+	if ref, ok := strings.CutPrefix(fmt.Sprint(err), "non-fast-forward update: "); ok {
+		fmt.Printf("Warning: %q: ref %s has diverged from the source, and force-push is disabled\n", name, ref)
 	}
-	host := strings.ToLower(u.Hostname())
-	client := github.NewClient(nil).WithAuthToken(tgtAccessToken)
-	if host != "github.com" {
-		client, err = client.WithEnterpriseURLs(u.Scheme+"://"+host, u.Scheme+"://"+host)
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		err = fmt.Errorf("failed to push to target: %w", err)
+		if tgtCreated && opts.TgtDeleteOnFailure {
+			// Don't leave an empty repo behind, it would be treated as existing by the next sync.
+			fmt.Printf("Deleting %q, because the push failed.\n", tgt)
+			if deleteErr := rc.DeleteRepo(ctx, owner, name); deleteErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to delete target repo: %w", deleteErr))
+			}
+		}
+		return status, err
+	}
+	if logChanges {
+		logBranchChanges(repo, name, refSpecs, refsBefore)
+	}
+	phase = "sync"
+	switch {
+	case !tgtExists:
+		status = copyNew
+	case errors.Is(err, git.NoErrAlreadyUpToDate):
+		status = copyUnchanged
+	default:
+		status = copyUpdated
+	}
+
+	if opts.ChecksumVerify && !wiki {
+		if err = verifyPushedBranches(ctx, client, repo, owner, name); err != nil {
+			return status, fmt.Errorf("failed to verify push: %w", err)
+		}
+	}
+
+	if opts.SyncDeployKeys && !wiki {
+		srcRepo, err := newRepoRef(clients, src, srcAccessToken)
 		if err != nil {
-			return fmt.Errorf("failed to set enterprise domain: %w", err)
+			return status, err
+		}
+		if err = syncDeployKeys(ctx, srcRepo, repoRef{Client: client, Host: u.Hostname(), Owner: owner, Name: name}); err != nil {
+			return status, fmt.Errorf("failed to sync deploy keys: %w", err)
 		}
 	}
 
-	// Get the name.
-	owner, name := path.Split(u.Path)
-	owner = strings.Trim(owner, "/")
-	name = strings.Trim(name, "/")
-	_, _, err = client.Repositories.Create(ctx, owner, &github.Repository{
-		Name:        &name,
-		Description: ptr(fmt.Sprintf("Mirror of %s", src)),
-		Visibility:  &tgtVisibility,
+	if opts.LabelSync && !wiki {
+		srcRepo, err := newRepoRef(clients, src, srcAccessToken)
+		if err != nil {
+			return status, err
+		}
+		if err = syncLabels(ctx, srcRepo, repoRef{Client: client, Host: u.Hostname(), Owner: owner, Name: name}, opts.LabelSyncDelete); err != nil {
+			return status, fmt.Errorf("failed to sync labels: %w", err)
+		}
+	}
+
+	if opts.SyncRulesets && !wiki {
+		srcRepo, err := newRepoRef(clients, src, srcAccessToken)
+		if err != nil {
+			return status, err
+		}
+		if err = syncRulesets(ctx, srcRepo, repoRef{Client: client, Host: u.Hostname(), Owner: owner, Name: name}, opts.TgtVersion); err != nil {
+			return status, fmt.Errorf("failed to sync rulesets: %w", err)
+		}
+	}
+
+	if opts.SyncSecurity && !wiki {
+		srcRepo, err := newRepoRef(clients, src, srcAccessToken)
+		if err != nil {
+			return status, err
+		}
+		if err = syncSecuritySettings(ctx, srcRepo, repoRef{Client: client, Host: u.Hostname(), Owner: owner, Name: name}); err != nil {
+			return status, fmt.Errorf("failed to sync security settings: %w", err)
+		}
+	}
+
+	return status, nil
+}
+
+// repoRef identifies a repo, and the API client used to access it.
+type repoRef struct {
+	Client *github.Client
+	Host   string
+	Owner  string
+	Name   string
+}
+
+func newRepoRef(clients *clientFactory, repoURL, token string) (r repoRef, err error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return r, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if r.Client, err = clients.NewGitHubClient(u, token); err != nil {
+		return r, err
+	}
+	r.Host = u.Hostname()
+	r.Owner, r.Name = path.Split(u.Path)
+	r.Owner = strings.Trim(r.Owner, "/")
+	r.Name = strings.TrimSuffix(strings.Trim(r.Name, "/"), ".git")
+	return r, nil
+}
+
+// createTargetRepo creates the target repo. It returns false if the repo was created by something else after it was
+// found to be missing, e.g. another sync.
+func createTargetRepo(ctx context.Context, rc RepoClient, owner, name, src string, opts copyOptions) (created bool, err error) {
+	newRepo := Repo{
+		Name:        name,
+		Description: mirrorDescription(src, opts.MirrorDescriptionSuffix),
+		Visibility:  opts.TgtVisibility,
+	}
+	err = retryWhileMigrating(ctx, opts.MigrationWaitTimeout, name, func() error {
+		return rc.CreateRepo(ctx, owner, newRepo)
 	})
-	if err != nil && !strings.Contains(err.Error(), "name already exists on this account") {
+	if errors.Is(err, errRepoExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create target repo: %w", err)
+	}
+	return true, nil
+}
+
+// initEmptyTarget creates the target with a .gitignore file, used when the source has no commits yet.
+func initEmptyTarget(ctx context.Context, client *github.Client, owner, name, src string, opts copyOptions) error {
+	if _, _, err := client.Gitignores.Get(ctx, opts.TgtInitGitignore); err != nil {
+		return fmt.Errorf("failed to get gitignore template %q: %w", opts.TgtInitGitignore, err)
+	}
+	fmt.Printf("Source %q is empty, creating target with a %s .gitignore.\n", src, opts.TgtInitGitignore)
+	newRepo := &github.Repository{
+		Name:              &name,
+		Description:       ptr(mirrorDescription(src, opts.MirrorDescriptionSuffix)),
+		AutoInit:          ptr(true),
+		GitignoreTemplate: ptr(opts.TgtInitGitignore),
+	}
+	setVisibility(newRepo, opts.TgtVisibility, opts.TgtVersion)
+	if _, err := createRepo(ctx, client, owner, newRepo, opts.ChecksumAlgo); err != nil {
 		return fmt.Errorf("failed to create target repo: %w", err)
 	}
+	return nil
+}
 
-	// Push to target.
-	err = repo.Push(&git.PushOptions{
-		RemoteURL: tgt,
-		Auth: &http.BasicAuth{
-			Username: "git",
-			Password: tgtAccessToken,
-		},
-		Force:      true,
-		FollowTags: true,
-		Progress:   os.Stdout,
+// setVisibility sets the visibility of a repo to be created. Servers that don't support the visibility field only
+// have the private flag, so internal repos are created as private, rather than being exposed publicly.
+func setVisibility(r *github.Repository, visibility string, v serverVersion) {
+	if v.Supports(featureRepoVisibility) {
+		r.Visibility = ptr(visibility)
+		return
+	}
+	r.Private = ptr(visibility != "public")
+}
+
+func isNotFound(err error) bool {
+	var errResp *github.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == 404
+}
+
+func filterCommitMessages(ctx context.Context, repo *git.Repository, dir, src, script string) (*git.Repository, error) {
+	callback, err := os.ReadFile(script)
+	if err != nil {
+		return repo, fmt.Errorf("failed to read script: %w", err)
+	}
+	args, err := filterRepoArgs(repo, string(callback))
+	if err != nil {
+		return repo, err
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return repo, fmt.Errorf("git filter-repo failed: %w", err)
+	}
+	// Re-open the repo, since filter-repo has rewritten the refs and objects on disk.
+	if repo, err = git.PlainOpen(dir); err != nil {
+		return repo, err
+	}
+	// The persistent work dir fetches from origin to update the clone.
+	if err = ensureOriginRemote(repo, src); err != nil {
+		return repo, err
+	}
+	return repo, nil
+}
+
+// filterRepoArgs returns the git filter-repo arguments that rewrite the messages of the local branches and tags.
+// Passing the refs with --refs stops filter-repo from rewriting the origin remote's refs, removing the remote, and
+// pruning the original objects, so that a clone kept in the persistent work dir can be updated from origin.
+func filterRepoArgs(repo *git.Repository, callback string) (args []string, err error) {
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+	var names []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && (ref.Name().IsBranch() || ref.Name().IsTag()) {
+			names = append(names, ref.Name().String())
+		}
+		return nil
 	})
-	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
-		return fmt.Errorf("failed to push to target: %w", err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
 	}
+	sort.Strings(names)
+	args = []string{"filter-repo", "--force", "--message-callback", callback, "--refs"}
+	return append(args, names...), nil
+}
 
+// ensureOriginRemote adds the origin remote, if it's missing.
+func ensureOriginRemote(repo *git.Repository, src string) error {
+	_, err := repo.Remote("origin")
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, git.ErrRemoteNotFound) {
+		return fmt.Errorf("failed to get origin remote: %w", err)
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name:  "origin",
+		URLs:  []string{src},
+		Fetch: []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add origin remote: %w", err)
+	}
 	return nil
 }
 
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func parseCopyOptions(t *testing.T, args ...string) (o copyOptions) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	return o
+}
+
+func TestCopyOptionsValidateNewRepoDepth(t *testing.T) {
+	tests := []struct {
+		depth   string
+		wantErr bool
+	}{
+		{depth: "0", wantErr: false},
+		{depth: "1", wantErr: true},
+		{depth: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.depth, func(t *testing.T) {
+			o := parseCopyOptions(t, "-new-repo-depth", tt.depth)
+			var gotErr bool
+			for _, e := range o.Validate() {
+				if strings.HasPrefix(e, "new-repo-depth:") {
+					gotErr = true
+				}
+			}
+			if gotErr != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, o.Validate())
+			}
+		})
+	}
+}
+
+// newTestRepo creates a non-bare repo with a commit for each of the messages.
+func newTestRepo(t *testing.T, dir string, messages ...string) *git.Repository {
+	t.Helper()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	when := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, msg := range messages {
+		if err = os.WriteFile(filepath.Join(dir, "file.txt"), []byte(msg), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err = w.Add("file.txt"); err != nil {
+			t.Fatalf("failed to add file: %v", err)
+		}
+		sig := &object.Signature{Name: "Test", Email: "test@example.com", When: when.AddDate(0, 0, i)}
+		if _, err = w.Commit(msg, &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+	}
+	return repo
+}
+
+// TestShallowPushIsRejected checks the reason that new-repo-depth must be 0. If go-git starts supporting pushes from
+// shallow clones, this test fails, and the flag can accept other depths.
+func TestShallowPushIsRejected(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	newTestRepo(t, src, "first", "second", "third")
+	tgt := filepath.Join(dir, "tgt")
+	if _, err := git.PlainInit(tgt, true); err != nil {
+		t.Fatalf("failed to init target: %v", err)
+	}
+
+	clone, err := git.PlainClone(filepath.Join(dir, "clone"), false, &git.CloneOptions{URL: "file://" + src, Depth: 1})
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	err = clone.Push(&git.PushOptions{RemoteURL: "file://" + tgt})
+	if err == nil {
+		t.Fatal("expected the push from a shallow clone to a new repo to fail")
+	}
+	if !strings.Contains(err.Error(), "missing necessary objects") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A full clone can be pushed.
+	full, err := git.PlainClone(filepath.Join(dir, "full"), false, &git.CloneOptions{URL: "file://" + src})
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	if err = full.Push(&git.PushOptions{RemoteURL: "file://" + tgt}); err != nil {
+		t.Errorf("failed to push full clone: %v", err)
+	}
+}
+
+func TestFilterRepoArgsOnlyRewriteLocalRefs(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "src")
+	src := newTestRepo(t, srcDir, "first")
+	head, err := src.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	if _, err = src.CreateTag("v1", head.Hash(), nil); err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+	clone, err := git.PlainClone(filepath.Join(dir, "clone"), false, &git.CloneOptions{URL: "file://" + srcDir})
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+
+	args, err := filterRepoArgs(clone, "return message")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "filter-repo --force --message-callback return message --refs refs/heads/master refs/tags/v1"
+	if strings.Join(args, " ") != want {
+		t.Errorf("expected %q, got %q", want, strings.Join(args, " "))
+	}
+}
+
+func TestEnsureOriginRemote(t *testing.T) {
+	dir := t.TempDir()
+	repo := newTestRepo(t, dir, "first")
+	const src = "https://github.com/org/repo"
+
+	// git filter-repo removes the origin remote when it rewrites a whole repo.
+	if err := ensureOriginRemote(repo, src); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		t.Fatalf("expected the origin remote to be added: %v", err)
+	}
+	if urls := remote.Config().URLs; len(urls) != 1 || urls[0] != src {
+		t.Errorf("expected the origin URL to be %q, got %v", src, urls)
+	}
+	// An existing remote is left unchanged.
+	if err = ensureOriginRemote(repo, "https://github.com/org/other"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remote, _ = repo.Remote("origin"); remote.Config().URLs[0] != src {
+		t.Errorf("expected the origin URL to be unchanged, got %v", remote.Config().URLs)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// migrateToMirror updates an existing target repo, which may have been changed since it was copied, to mirror the
+// branches and tags of the source. Each ref is pushed on its own, and only refs that have diverged are force-pushed,
+// so a failure leaves the other refs updated. Refs that are only on the target are left unchanged.
+func migrateToMirror(args []string) error {
+	fs := flag.NewFlagSet("migrate-to-mirror", flag.ContinueOnError)
+	srcAccessTokenFlag := fs.String("src-token", "", "Personal access token for pulling from the source")
+	srcURLFlag := fs.String("src-url", "", "URL of the source repo, e.g. https://github.com/org/repo")
+	tgtAccessTokenFlag := fs.String("tgt-token", "", "Personal access token for pushing to the target")
+	tgtURLFlag := fs.String("tgt-url", "", "URL of the existing target repo, e.g. https://github.enterprise.com/org/repo")
+	dryRunFlag := fs.Bool("dry-run", false, "Set to true to print the refs that would be pushed, without pushing them")
+	var logOpts logOptions
+	logOpts.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var errs []string
+	if *srcAccessTokenFlag == "" {
+		errs = append(errs, "Missing src-token flag")
+	}
+	if *srcURLFlag == "" {
+		errs = append(errs, "Missing src-url flag")
+	}
+	if *tgtAccessTokenFlag == "" {
+		errs = append(errs, "Missing tgt-token flag")
+	}
+	if *tgtURLFlag == "" {
+		errs = append(errs, "Missing tgt-url flag")
+	}
+	errs = append(errs, logOpts.Validate()...)
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid or missing params:\n -%s", strings.Join(errs, "\n -"))
+	}
+
+	logOpts.Configure()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer cancel()
+
+	dir, err := os.MkdirTemp(os.TempDir(), "src_mirror_")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Fetch the branches and tags of the source into a bare repo, with the same names as in the source.
+	fmt.Printf("Cloning %q...\n", *srcURLFlag)
+	repo, err := git.PlainInit(dir, true)
+	if err != nil {
+		return fmt.Errorf("failed to create bare repo: %w", err)
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{*srcURLFlag},
+		Fetch: []config.RefSpec{
+			"+refs/heads/*:refs/heads/*",
+			"+refs/tags/*:refs/tags/*",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create remote: %w", err)
+	}
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		Auth: &http.BasicAuth{
+			Username: gitUsername(*srcURLFlag),
+			Password: *srcAccessTokenFlag,
+		},
+		Progress: newGitProgressWriter(*srcURLFlag),
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+
+	tgtRefs, err := listRemoteRefs(ctx, *tgtURLFlag, *tgtAccessTokenFlag)
+	if err != nil {
+		return err
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		return fmt.Errorf("failed to list refs: %w", err)
+	}
+	var names []plumbing.ReferenceName
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && (ref.Name().IsBranch() || ref.Name().IsTag()) {
+			names = append(names, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+
+	var unchanged, updated, forced int
+	var failed []string
+	for _, name := range names {
+		ref, err := repo.Reference(name, false)
+		if err != nil {
+			return fmt.Errorf("failed to get %s: %w", name, err)
+		}
+		tgtHash, ok := tgtRefs[name.String()]
+		if ok && tgtHash == ref.Hash() {
+			unchanged++
+			continue
+		}
+		// Tags don't have a history, so a tag that points somewhere else on the target has diverged.
+		ff := !ok
+		if ok && name.IsBranch() {
+			if ff, err = isFastForward(repo, name, tgtHash); err != nil {
+				return fmt.Errorf("failed to check %s: %w", name, err)
+			}
+		}
+		refSpec := config.RefSpec(fmt.Sprintf("%[1]s:%[1]s", name))
+		action := "Updating"
+		if !ff {
+			refSpec = "+" + refSpec
+			action = "Force-pushing"
+		}
+		fmt.Printf("%s %s...\n", action, name)
+		if *dryRunFlag {
+			continue
+		}
+		err = repo.PushContext(ctx, &git.PushOptions{
+			RemoteURL: *tgtURLFlag,
+			RefSpecs:  []config.RefSpec{refSpec},
+			Auth: &http.BasicAuth{
+				Username: "git",
+				Password: *tgtAccessTokenFlag,
+			},
+		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			fmt.Printf("Warning: failed to push %s: %v\n", name, err)
+			failed = append(failed, name.String())
+			continue
+		}
+		if ff {
+			updated++
+		} else {
+			forced++
+		}
+	}
+	fmt.Printf("Refs unchanged: %d, updated: %d, force-pushed: %d, failed: %d\n", unchanged, updated, forced, len(failed))
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to push %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
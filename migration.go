@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// isBeingMigrated returns true if the error is GHES reporting that the repo is still being imported.
+func isBeingMigrated(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "repository is being migrated")
+}
+
+// retryWhileMigrating calls f until it doesn't fail because the repo is being migrated, waiting 5s, 10s, 20s and so
+// on between attempts, for up to timeout in total.
+func retryWhileMigrating(ctx context.Context, timeout time.Duration, name string, f func() error) error {
+	deadline := time.Now().Add(timeout)
+	delay := 5 * time.Second
+	for {
+		err := f()
+		if !isBeingMigrated(err) {
+			return err
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("gave up waiting %v for the migration of %q to finish: %w", timeout, name, err)
+		}
+		fmt.Printf("Waiting %v for %q, because it's being migrated...\n", delay, name)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/google/go-github/v55/github"
+)
+
+// detectObjectFormat returns the hash algorithm of a remote repo, sha1 or sha256, from the object-format capability
+// advertised by the server. Servers that don't advertise it only support sha1.
+func detectObjectFormat(ctx context.Context, remoteURL, accessToken string) (format string, err error) {
+	ep, err := transport.NewEndpoint(remoteURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url %q: %w", remoteURL, err)
+	}
+	c, err := client.NewClient(ep)
+	if err != nil {
+		return "", err
+	}
+	s, err := c.NewUploadPackSession(ep, &http.BasicAuth{
+		Username: gitUsername(remoteURL),
+		Password: accessToken,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %q: %w", remoteURL, err)
+	}
+	defer s.Close()
+	ar, err := s.AdvertisedReferencesContext(ctx)
+	if err != nil && !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return "", fmt.Errorf("failed to get capabilities of %q: %w", remoteURL, err)
+	}
+	if ar != nil {
+		if values := ar.Capabilities.Get(capability.ObjectFormat); len(values) > 0 {
+			return values[0], nil
+		}
+	}
+	return "sha1", nil
+}
+
+// createRepo creates a repo. The object_format field isn't part of go-github's Repository, so repos that don't use
+// the default sha1 format are created with a custom request.
+func createRepo(ctx context.Context, client *github.Client, owner string, r *github.Repository, objectFormat string) (created *github.Repository, err error) {
+	if objectFormat == "" || objectFormat == "sha1" {
+		created, _, err = client.Repositories.Create(ctx, owner, r)
+		return created, err
+	}
+	body := struct {
+		*github.Repository
+		ObjectFormat string `json:"object_format"`
+	}{r, objectFormat}
+	u := "user/repos"
+	if owner != "" {
+		u = fmt.Sprintf("orgs/%v/repos", owner)
+	}
+	req, err := client.NewRequest("POST", u, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	created = new(github.Repository)
+	if _, err = client.Do(ctx, req, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// oidcAuth gets a Github App installation token in a Github Actions workflow, without a long-lived secret. The job's
+// OIDC token is requested from the Actions runtime, and exchanged for an installation token by a token exchange
+// service at exchangeURL, which checks the OIDC token's claims (e.g. repository and ref) against its policy. The
+// service is called with the OIDC token as a bearer token, and must respond with JSON in the form {"token": "..."},
+// like octo-sts.
+func oidcAuth(ctx context.Context, clients *clientFactory, exchangeURL, audience string) (token string, err error) {
+	requestURL, requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return "", errors.New("ACTIONS_ID_TOKEN_REQUEST_URL and ACTIONS_ID_TOKEN_REQUEST_TOKEN are not set, the workflow needs the id-token: write permission")
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ACTIONS_ID_TOKEN_REQUEST_URL: %w", err)
+	}
+	if audience != "" {
+		q := u.Query()
+		q.Set("audience", audience)
+		u.RawQuery = q.Encode()
+	}
+	httpClient := clients.NewHTTPClient()
+	var idToken struct {
+		Value string `json:"value"`
+	}
+	if err = getJSON(ctx, httpClient, u.String(), requestToken, &idToken); err != nil {
+		return "", fmt.Errorf("failed to get OIDC token: %w", err)
+	}
+	var exchanged struct {
+		Token string `json:"token"`
+	}
+	if err = getJSON(ctx, httpClient, exchangeURL, idToken.Value, &exchanged); err != nil {
+		return "", fmt.Errorf("failed to exchange OIDC token: %w", err)
+	}
+	if exchanged.Token == "" {
+		return "", fmt.Errorf("no token returned by %s", exchangeURL)
+	}
+	return exchanged.Token, nil
+}
+
+func getJSON(ctx context.Context, httpClient *http.Client, u, bearerToken string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+bearerToken)
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, body)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// syncOrgMembership gives each member of the source org the same role in the target org. It relies on users having
+// the same login on both servers, e.g. because they're provisioned into GHES via SCIM.
+func syncOrgMembership(ctx context.Context, clients *clientFactory, srcURL, srcAccessToken, tgtURL, tgtAccessToken string) error {
+	src, err := url.Parse(srcURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse source url: %w", err)
+	}
+	tgt, err := url.Parse(tgtURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse target url: %w", err)
+	}
+	srcClient, err := clients.NewGitHubClient(src, srcAccessToken)
+	if err != nil {
+		return err
+	}
+	tgtClient, err := clients.NewGitHubClient(tgt, tgtAccessToken)
+	if err != nil {
+		return err
+	}
+	srcOrg := strings.Split(strings.Trim(src.Path, "/"), "/")[0]
+	tgtOrg := strings.Split(strings.Trim(tgt.Path, "/"), "/")[0]
+
+	for _, role := range []string{"admin", "member"} {
+		members, err := listOrgMembers(ctx, srcClient, srcOrg, role)
+		if err != nil {
+			return fmt.Errorf("failed to list source org %ss: %w", role, err)
+		}
+		for _, m := range members {
+			login := m.GetLogin()
+			membership, _, err := tgtClient.Organizations.GetOrgMembership(ctx, login, tgtOrg)
+			if err != nil && !isNotFound(err) {
+				return fmt.Errorf("failed to get target org membership of %q: %w", login, err)
+			}
+			if err == nil && membership.GetRole() == role {
+				continue
+			}
+			fmt.Printf("Setting %q role in %q to %s.\n", login, tgtOrg, role)
+			if _, _, err = tgtClient.Organizations.EditOrgMembership(ctx, login, tgtOrg, &github.Membership{Role: ptr(role)}); err != nil {
+				return fmt.Errorf("failed to set target org membership of %q: %w", login, err)
+			}
+		}
+	}
+	return nil
+}
+
+func listOrgMembers(ctx context.Context, client *github.Client, org, role string) (members []*github.User, err error) {
+	opts := &github.ListMembersOptions{
+		Role:        role,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		page, resp, err := client.Organizations.ListMembers(ctx, org, opts)
+		if err != nil {
+			return members, err
+		}
+		members = append(members, page...)
+		if resp.NextPage == 0 {
+			return members, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// syncPackages copies the tagged versions of the source org's container packages that belong to the copied repos to
+// the target's container registry. Other package types use different registry protocols, so aren't copied.
+func syncPackages(ctx context.Context, clients *clientFactory, srcURL, srcAccessToken, tgtURL, tgtAccessToken string, repos []Repo) error {
+	src, err := url.Parse(srcURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse source url: %w", err)
+	}
+	tgt, err := url.Parse(tgtURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse target url: %w", err)
+	}
+	client, err := clients.NewGitHubClient(src, srcAccessToken)
+	if err != nil {
+		return err
+	}
+	srcOrg := strings.Split(strings.Trim(src.Path, "/"), "/")[0]
+	tgtOrg := strings.Split(strings.Trim(tgt.Path, "/"), "/")[0]
+
+	copied := make(map[string]bool, len(repos))
+	for _, r := range repos {
+		copied[strings.ToLower(r.Name)] = true
+	}
+
+	packages, err := listContainerPackages(ctx, client, srcOrg)
+	if err != nil {
+		return fmt.Errorf("failed to list packages: %w", err)
+	}
+	srcRegistry := newRegistryClient(containerRegistryHost(src), srcAccessToken)
+	tgtRegistry := newRegistryClient(containerRegistryHost(tgt), tgtAccessToken)
+	for _, p := range packages {
+		if !copied[strings.ToLower(p.GetRepository().GetName())] {
+			continue
+		}
+		tags, err := listContainerPackageTags(ctx, client, srcOrg, p.GetName())
+		if err != nil {
+			return fmt.Errorf("failed to list versions of package %q: %w", p.GetName(), err)
+		}
+		srcName := strings.ToLower(srcOrg + "/" + p.GetName())
+		tgtName := strings.ToLower(tgtOrg + "/" + p.GetName())
+		for _, tag := range tags {
+			fmt.Printf("Copying container image %s:%s to %s/%s:%s...\n", srcName, tag, tgtRegistry.host, tgtName, tag)
+			if err = copyImage(ctx, srcRegistry, srcName, tgtRegistry, tgtName, tag); err != nil {
+				return fmt.Errorf("failed to copy container image %s:%s: %w", srcName, tag, err)
+			}
+		}
+	}
+	return nil
+}
+
+func listContainerPackages(ctx context.Context, client *github.Client, org string) (packages []*github.Package, err error) {
+	opts := &github.PackageListOptions{
+		PackageType: ptr("container"),
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		page, resp, err := client.Organizations.ListPackages(ctx, org, opts)
+		if err != nil {
+			return packages, err
+		}
+		packages = append(packages, page...)
+		if resp.NextPage == 0 {
+			return packages, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// listContainerPackageTags returns the tags of all versions of a package. Untagged versions are only referenced by
+// digest from other manifests, so are copied along with them.
+func listContainerPackageTags(ctx context.Context, client *github.Client, org, name string) (tags []string, err error) {
+	opts := &github.PackageListOptions{
+		State:       ptr("active"),
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		versions, resp, err := client.Organizations.PackageGetAllVersions(ctx, org, "container", name, opts)
+		if err != nil {
+			return tags, err
+		}
+		for _, v := range versions {
+			tags = append(tags, v.GetMetadata().GetContainer().Tags...)
+		}
+		if resp.NextPage == 0 {
+			return tags, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
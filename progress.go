@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// syncProgress tracks the progress of a sync, so that it can be written to the file set by -progress-file.
+type syncProgress struct {
+	mutex      sync.Mutex
+	eta        *etaEstimator
+	inProgress map[string]bool
+	failed     []string
+}
+
+func newSyncProgress(total, concurrency int) *syncProgress {
+	return &syncProgress{
+		eta:        newETAEstimator(total, concurrency),
+		inProgress: make(map[string]bool),
+	}
+}
+
+// Start records that a repo has started copying.
+func (p *syncProgress) Start(name string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.inProgress[name] = true
+}
+
+// Finish records that a repo has finished copying, and returns the progress counter, e.g. "[47/312] (~4h23m remaining)".
+func (p *syncProgress) Finish(name string, d time.Duration, err error) string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.inProgress, name)
+	if err != nil {
+		p.failed = append(p.failed, name)
+	}
+	p.eta.Add(d)
+	return p.eta.String()
+}
+
+type progressFile struct {
+	Total      int      `json:"total"`
+	Completed  int      `json:"completed"`
+	InProgress []string `json:"in_progress"`
+	Failed     []string `json:"failed"`
+	ETASeconds int64    `json:"eta_seconds"`
+}
+
+func (p *syncProgress) MarshalJSON() ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	f := progressFile{
+		Total:      p.eta.total,
+		Completed:  p.eta.completed,
+		InProgress: []string{},
+		Failed:     append([]string{}, p.failed...),
+	}
+	for name := range p.inProgress {
+		f.InProgress = append(f.InProgress, name)
+	}
+	sort.Strings(f.InProgress)
+	if p.eta.completed > 0 {
+		f.ETASeconds = int64(p.eta.Remaining().Seconds())
+	}
+	return json.Marshal(f)
+}
+
+// WriteFile writes the progress atomically, so that readers never see partial JSON.
+func (p *syncProgress) WriteFile(fileName string) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress: %w", err)
+	}
+	return writeFileAtomic(fileName, data)
+}
+
+// WriteFileEvery writes the progress file every interval until the context is cancelled, then writes it a final
+// time.
+func (p *syncProgress) WriteFileEvery(ctx context.Context, fileName string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.WriteFile(fileName); err != nil {
+			fmt.Printf("Failed to write progress file: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			if err := p.WriteFile(fileName); err != nil {
+				fmt.Printf("Failed to write progress file: %v\n", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
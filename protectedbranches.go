@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// parseProtectedBranches parses a comma separated list of branch names or globs, e.g. main,release/*.
+func parseProtectedBranches(s string) (patterns []string, err error) {
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err = path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+func isProtectedBranch(patterns []string, branch string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeProtectedBranches returns the refspecs to push, without the protected branches whose push would overwrite
+// commits on the target, given the target's refs. If refSpecs is empty, every local branch is pushed, which is the
+// default of go-git. Refspecs that don't push a branch, e.g. for tags, are kept. The returned refspecs are empty if
+// there's nothing left to push.
+func excludeProtectedBranches(repo *git.Repository, refSpecs []config.RefSpec, tgtRefs map[string]plumbing.Hash, patterns []string) (filtered []config.RefSpec, skipped []string, err error) {
+	if len(refSpecs) == 0 {
+		branches, err := repo.Branches()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list branches: %w", err)
+		}
+		err = branches.ForEach(func(ref *plumbing.Reference) error {
+			refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("%[1]s:%[1]s", ref.Name())))
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	filtered = []config.RefSpec{}
+	for _, rs := range refSpecs {
+		dst := plumbing.ReferenceName(rs.Dst(plumbing.ReferenceName(rs.Src())))
+		if !dst.IsBranch() || !isProtectedBranch(patterns, dst.Short()) {
+			filtered = append(filtered, rs)
+			continue
+		}
+		ff, err := isFastForward(repo, plumbing.ReferenceName(rs.Src()), tgtRefs[dst.String()])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check %s: %w", dst, err)
+		}
+		if !ff {
+			skipped = append(skipped, dst.Short())
+			continue
+		}
+		filtered = append(filtered, rs)
+	}
+	return filtered, skipped, nil
+}
+
+// isFastForward returns true if pushing the local ref wouldn't remove any commits from a target ref at tgtHash. A
+// zero hash means the target doesn't have the ref.
+func isFastForward(repo *git.Repository, local plumbing.ReferenceName, tgtHash plumbing.Hash) (bool, error) {
+	if tgtHash.IsZero() {
+		return true, nil
+	}
+	ref, err := repo.Reference(local, true)
+	if err != nil {
+		return false, err
+	}
+	if ref.Hash() == tgtHash {
+		return true, nil
+	}
+	tgtCommit, err := repo.CommitObject(tgtHash)
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		// Commits that aren't in the source can't be in its history.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	localCommit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return false, err
+	}
+	return tgtCommit.IsAncestor(localCommit)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pushThrottle spaces out pushes across all of the concurrent copies, so that the target receives at most one push
+// per interval. A nil pushThrottle doesn't limit pushes.
+type pushThrottle struct {
+	m        sync.Mutex
+	interval time.Duration
+	// last is the time that the previous push was allowed, zero if there hasn't been one.
+	last time.Time
+}
+
+func newPushThrottle(interval time.Duration) *pushThrottle {
+	if interval <= 0 {
+		return nil
+	}
+	return &pushThrottle{
+		interval: interval,
+	}
+}
+
+// Wait blocks until the next push is allowed, which is immediately for the first push, and then at least an interval
+// after the previous push was allowed. Callers wait in turn, so two pushes can't be allowed less than an interval apart.
+func (pt *pushThrottle) Wait(ctx context.Context) error {
+	if pt == nil {
+		return nil
+	}
+	pt.m.Lock()
+	defer pt.m.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !pt.last.IsZero() {
+		if remaining := pt.interval - time.Since(pt.last); remaining > 0 {
+			timer := time.NewTimer(remaining)
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	pt.last = time.Now()
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPushThrottle(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	pt := newPushThrottle(interval)
+	ctx := context.Background()
+
+	start := time.Now()
+	if err := pt.Wait(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := time.Since(start); d >= interval {
+		t.Errorf("expected the first push to be allowed immediately, waited %v", d)
+	}
+	if err := pt.Wait(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := time.Since(start); d < interval {
+		t.Errorf("expected the second push to wait for the interval, waited %v", d)
+	}
+}
+
+func TestPushThrottleCancelled(t *testing.T) {
+	pt := newPushThrottle(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := pt.Wait(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancel()
+	if err := pt.Wait(ctx); err == nil {
+		t.Error("expected an error when the context is cancelled")
+	}
+}
+
+func TestPushThrottleDisabled(t *testing.T) {
+	if pt := newPushThrottle(0); pt != nil {
+		t.Errorf("expected a nil throttle for a zero interval, got %+v", pt)
+	}
+	var pt *pushThrottle
+	if err := pt.Wait(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// listRemoteRefs is the equivalent of `git ls-remote`, returning a map of ref name to hash.
+// Empty repos have no refs.
+func listRemoteRefs(ctx context.Context, remoteURL, accessToken string) (refs map[string]plumbing.Hash, err error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "remote",
+		URLs: []string{remoteURL},
+	})
+	list, err := remote.ListContext(ctx, &git.ListOptions{
+		Auth: &http.BasicAuth{
+			Username: gitUsername(remoteURL),
+			Password: accessToken,
+		},
+	})
+	if err != nil && !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return refs, fmt.Errorf("failed to list refs of %q: %w", remoteURL, err)
+	}
+	refs = make(map[string]plumbing.Hash, len(list))
+	for _, ref := range list {
+		if ref.Type() != plumbing.HashReference {
+			continue
+		}
+		refs[ref.Name().String()] = ref.Hash()
+	}
+	return refs, nil
+}
+
+// trackRemoteBranches creates a local branch for each branch fetched from origin, so that every branch of the source
+// is pushed, not just the checked out one. Local branches that have been deleted from the source are removed.
+func trackRemoteBranches(repo *git.Repository) error {
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return fmt.Errorf("failed to list refs: %w", err)
+	}
+	remote := make(map[string]plumbing.Hash)
+	var local []plumbing.ReferenceName
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		// origin/HEAD is a symbolic ref, so it's skipped.
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		if branch, ok := strings.CutPrefix(ref.Name().String(), "refs/remotes/origin/"); ok {
+			remote[branch] = ref.Hash()
+		}
+		if ref.Name().IsBranch() {
+			local = append(local, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for branch, h := range remote {
+		if err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), h)); err != nil {
+			return fmt.Errorf("failed to create branch %q: %w", branch, err)
+		}
+	}
+	for _, name := range local {
+		if _, ok := remote[name.Short()]; ok || name == head.Target() {
+			continue
+		}
+		if err = repo.Storer.RemoveReference(name); err != nil {
+			return fmt.Errorf("failed to remove branch %q: %w", name.Short(), err)
+		}
+	}
+	return nil
+}
+
+// pushRefSpecs returns the refspecs used to push the clone to the target. Every local branch, which
+// trackRemoteBranches creates for each branch of the source, and every tag is pushed. Listing the tags explicitly
+// also pushes lightweight tags, and the tags of branches that are already up to date, which go-git's FollowTags
+// misses. When the history is squashed, only the default branch is pushed, and it's always force-pushed, because the
+// squashed commit doesn't descend from the previous snapshot. Other branches and tags would copy the history, so
+// they're left unchanged on the target.
+func pushRefSpecs(repo *git.Repository, squash bool) (refSpecs []config.RefSpec, err error) {
+	if squash {
+		head, err := repo.Storer.Reference(plumbing.HEAD)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		return []config.RefSpec{config.RefSpec(fmt.Sprintf("+%[1]s:%[1]s", head.Target()))}, nil
+	}
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("%[1]s:%[1]s", ref.Name())))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	sort.Slice(refSpecs, func(i, j int) bool { return refSpecs[i] < refSpecs[j] })
+	return append(refSpecs, "refs/tags/*:refs/tags/*"), nil
+}
+
+type branchChange struct {
+	Branch string
+	Action string
+	From   string
+	To     string
+}
+
+// branchChanges compares the branches in two sets of refs.
+func branchChanges(before, after map[string]plumbing.Hash) (changes []branchChange) {
+	for name, to := range after {
+		ref := plumbing.ReferenceName(name)
+		if !ref.IsBranch() {
+			continue
+		}
+		from, ok := before[name]
+		if !ok {
+			changes = append(changes, branchChange{Branch: ref.Short(), Action: "added", To: to.String()})
+			continue
+		}
+		if from != to {
+			changes = append(changes, branchChange{Branch: ref.Short(), Action: "updated", From: from.String(), To: to.String()})
+		}
+	}
+	for name, from := range before {
+		ref := plumbing.ReferenceName(name)
+		if !ref.IsBranch() {
+			continue
+		}
+		if _, ok := after[name]; !ok {
+			changes = append(changes, branchChange{Branch: ref.Short(), Action: "deleted", From: from.String()})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Branch < changes[j].Branch
+	})
+	return changes
+}
+
+// pushedRefs returns the target's refs after a successful push, which are the refs before the push, updated with the
+// local refs that the refspecs push.
+func pushedRefs(repo *git.Repository, refSpecs []config.RefSpec, before map[string]plumbing.Hash) (after map[string]plumbing.Hash, err error) {
+	after = make(map[string]plumbing.Hash, len(before))
+	for name, h := range before {
+		after[name] = h
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+		for _, rs := range refSpecs {
+			if !rs.IsDelete() && rs.Match(ref.Name()) {
+				after[rs.Dst(ref.Name()).String()] = ref.Hash()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+	return after, nil
+}
+
+// logBranchChanges logs the branches added, updated or deleted on the target by a push at debug level, given the
+// target's refs before the push.
+func logBranchChanges(repo *git.Repository, name string, refSpecs []config.RefSpec, before map[string]plumbing.Hash) {
+	after, err := pushedRefs(repo, refSpecs, before)
+	if err != nil {
+		slog.Debug("Failed to get branch changes", slog.String("repo", name), slog.String("error", err.Error()))
+		return
+	}
+	for _, c := range branchChanges(before, after) {
+		slog.Debug("Branch changed",
+			slog.String("repo", name),
+			slog.String("branch", c.Branch),
+			slog.String("action", c.Action),
+			slog.String("from", c.From),
+			slog.String("to", c.To),
+		)
+	}
+}
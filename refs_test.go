@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// TestPushedRefsMatchSource checks that a clone with every branch tracked, pushed with the refspecs from pushRefSpecs,
+// produces a mirror that verify reports as matching the source.
+func TestPushedRefsMatchSource(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "src")
+	src := newTestRepo(t, srcDir, "first", "second")
+	head, err := src.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	first, err := src.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("failed to get commit: %v", err)
+	}
+	parent := first.ParentHashes[0]
+	// A branch that isn't checked out, a lightweight tag, and an annotated tag.
+	if err = src.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature"), parent)); err != nil {
+		t.Fatalf("failed to create branch: %v", err)
+	}
+	if _, err = src.CreateTag("v1", parent, nil); err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+	sig := &object.Signature{Name: "Test", Email: "test@example.com", When: first.Committer.When}
+	if _, err = src.CreateTag("v2", head.Hash(), &git.CreateTagOptions{Tagger: sig, Message: "v2"}); err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+	tgtDir := filepath.Join(dir, "tgt")
+	if _, err = git.PlainInit(tgtDir, true); err != nil {
+		t.Fatalf("failed to init target: %v", err)
+	}
+
+	clone, err := git.PlainClone(filepath.Join(dir, "clone"), false, &git.CloneOptions{URL: "file://" + srcDir})
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	if err = trackRemoteBranches(clone); err != nil {
+		t.Fatalf("failed to track branches: %v", err)
+	}
+	refSpecs, err := pushRefSpecs(clone, false)
+	if err != nil {
+		t.Fatalf("failed to get refspecs: %v", err)
+	}
+	err = clone.Push(&git.PushOptions{
+		RemoteURL: "file://" + tgtDir,
+		RefSpecs:  refSpecs,
+	})
+	if err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+
+	discrepancies, err := verifyMirror(context.Background(), "file://"+srcDir, "", "file://"+tgtDir, "")
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if len(discrepancies) > 0 {
+		t.Errorf("expected the target to match the source, got %v", discrepancies)
+	}
+}
+
+func TestTrackRemoteBranchesRemovesDeletedBranches(t *testing.T) {
+	dir := t.TempDir()
+	srcDir := filepath.Join(dir, "src")
+	src := newTestRepo(t, srcDir, "first")
+	head, err := src.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	clone, err := git.PlainClone(filepath.Join(dir, "clone"), false, &git.CloneOptions{URL: "file://" + srcDir})
+	if err != nil {
+		t.Fatalf("failed to clone: %v", err)
+	}
+	// A branch left behind by an earlier sync, that has since been deleted from the source.
+	stale := plumbing.NewBranchReferenceName("stale")
+	if err = clone.Storer.SetReference(plumbing.NewHashReference(stale, head.Hash())); err != nil {
+		t.Fatalf("failed to create branch: %v", err)
+	}
+	if err = trackRemoteBranches(clone); err != nil {
+		t.Fatalf("failed to track branches: %v", err)
+	}
+	if _, err = clone.Reference(stale, false); err == nil {
+		t.Error("expected the stale branch to be removed")
+	}
+	if _, err = clone.Reference(head.Name(), false); err != nil {
+		t.Errorf("expected the checked out branch to be kept: %v", err)
+	}
+}
+
+func TestPushRefSpecs(t *testing.T) {
+	dir := t.TempDir()
+	repo := newTestRepo(t, dir, "first")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature"), head.Hash())); err != nil {
+		t.Fatalf("failed to create branch: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		squash bool
+		want   []config.RefSpec
+	}{
+		{
+			name: "every branch and tag",
+			want: []config.RefSpec{"refs/heads/feature:refs/heads/feature", "refs/heads/master:refs/heads/master", "refs/tags/*:refs/tags/*"},
+		},
+		{
+			name:   "squashed default branch only",
+			squash: true,
+			want:   []config.RefSpec{"+refs/heads/master:refs/heads/master"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pushRefSpecs(repo, tt.squash)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPushRefSpecsKeepsTagsWhenBranchesAreProtected(t *testing.T) {
+	dir := t.TempDir()
+	repo := newTestRepo(t, dir, "first")
+	refSpecs, err := pushRefSpecs(repo, false)
+	if err != nil {
+		t.Fatalf("failed to get refspecs: %v", err)
+	}
+	// The target's master has a commit that isn't in the source.
+	tgtRefs := map[string]plumbing.Hash{"refs/heads/master": plumbing.NewHash("1111111111111111111111111111111111111111")}
+	filtered, skipped, err := excludeProtectedBranches(repo, refSpecs, tgtRefs, []string{"master"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(skipped) != "[master]" {
+		t.Errorf("expected master to be skipped, got %v", skipped)
+	}
+	if fmt.Sprint(filtered) != "[refs/tags/*:refs/tags/*]" {
+		t.Errorf("expected only the tags to be pushed, got %v", filtered)
+	}
+}
+
+func TestPushedRefsBranchChanges(t *testing.T) {
+	dir := t.TempDir()
+	repo := newTestRepo(t, dir, "first", "second")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("feature"), head.Hash())); err != nil {
+		t.Fatalf("failed to create branch: %v", err)
+	}
+	refSpecs, err := pushRefSpecs(repo, false)
+	if err != nil {
+		t.Fatalf("failed to get refspecs: %v", err)
+	}
+	old := plumbing.NewHash("1111111111111111111111111111111111111111")
+	before := map[string]plumbing.Hash{
+		"refs/heads/master": old,
+		// Branches that are only on the target aren't deleted by the push.
+		"refs/heads/target-only": old,
+	}
+
+	after, err := pushedRefs(repo, refSpecs, before)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := branchChanges(before, after)
+	want := []branchChange{
+		{Branch: "feature", Action: "added", To: head.Hash().String()},
+		{Branch: "master", Action: "updated", From: old.String(), To: head.Hash().String()},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if before["refs/heads/master"] != old {
+		t.Error("expected the refs before the push to be unchanged")
+	}
+}
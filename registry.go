@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// manifestMediaTypes are the manifest types that can be copied. Indexes and manifest lists reference other manifests,
+// while image manifests reference blobs.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// registryClient is a minimal Docker Registry HTTP API v2 client, used to copy container images.
+type registryClient struct {
+	host  string
+	token string
+	http  *http.Client
+	// bearerTokens are the tokens issued by the registry's auth server, by scope.
+	bearerTokensMutex sync.Mutex
+	bearerTokens      map[string]string
+}
+
+// containerRegistryHost returns the host of the container registry of a Github server. GHES is assumed to use
+// subdomain isolation.
+func containerRegistryHost(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	if host == "github.com" {
+		return "ghcr.io"
+	}
+	return "containers." + host
+}
+
+func newRegistryClient(host, token string) *registryClient {
+	return &registryClient{
+		host:         host,
+		token:        token,
+		http:         &http.Client{},
+		bearerTokens: make(map[string]string),
+	}
+}
+
+// do sends the request, authenticating with a bearer token if the registry asks for one. Requests with a body are
+// only sent once, so the caller must make sure the client already has a token for the scope, e.g. by starting an
+// upload.
+func (c *registryClient) do(req *http.Request, scope string) (resp *http.Response, err error) {
+	c.bearerTokensMutex.Lock()
+	bearer, ok := c.bearerTokens[scope]
+	c.bearerTokensMutex.Unlock()
+	if ok {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	if resp, err = c.http.Do(req); err != nil {
+		return resp, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized || req.Body != nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+	if bearer, err = c.authenticate(req.Context(), resp.Header.Get("WWW-Authenticate"), scope); err != nil {
+		return nil, err
+	}
+	c.bearerTokensMutex.Lock()
+	c.bearerTokens[scope] = bearer
+	c.bearerTokensMutex.Unlock()
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+bearer)
+	return c.http.Do(req)
+}
+
+// authenticate gets a bearer token from the auth server named in the WWW-Authenticate challenge.
+func (c *registryClient) authenticate(ctx context.Context, challenge, scope string) (bearer string, err error) {
+	params, ok := parseBearerChallenge(challenge)
+	if !ok {
+		return "", fmt.Errorf("unsupported registry authentication challenge %q", challenge)
+	}
+	u, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", fmt.Errorf("failed to parse registry auth realm: %w", err)
+	}
+	q := u.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	q.Set("scope", scope)
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create registry auth request: %w", err)
+	}
+	req.SetBasicAuth("git", c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get registry token: unexpected status %s", resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// parseBearerChallenge parses a header such as: Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="..."
+func parseBearerChallenge(challenge string) (params map[string]string, ok bool) {
+	rest, ok := strings.CutPrefix(challenge, "Bearer ")
+	if !ok {
+		return nil, false
+	}
+	params = make(map[string]string)
+	for _, kv := range strings.Split(rest, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			continue
+		}
+		params[k] = strings.Trim(v, `"`)
+	}
+	return params, params["realm"] != ""
+}
+
+func (c *registryClient) url(format string, args ...any) string {
+	return "https://" + c.host + fmt.Sprintf(format, args...)
+}
+
+func pullScope(name string) string {
+	return "repository:" + name + ":pull"
+}
+
+func pushScope(name string) string {
+	return "repository:" + name + ":pull,push"
+}
+
+type manifestDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type manifest struct {
+	Manifests []manifestDescriptor `json:"manifests"`
+	Config    *manifestDescriptor  `json:"config"`
+	Layers    []manifestDescriptor `json:"layers"`
+}
+
+func (c *registryClient) getManifest(ctx context.Context, name, reference string) (body []byte, mediaType string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("/v2/%s/manifests/%s", name, reference), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	resp, err := c.do(req, pullScope(name))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get manifest %s:%s: %w", name, reference, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to get manifest %s:%s: unexpected status %s", name, reference, resp.Status)
+	}
+	if body, err = io.ReadAll(resp.Body); err != nil {
+		return nil, "", fmt.Errorf("failed to read manifest %s:%s: %w", name, reference, err)
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+func (c *registryClient) putManifest(ctx context.Context, name, reference, mediaType string, body []byte) error {
+	// Make sure there's a push token, since requests with a body aren't retried.
+	if err := c.ensureToken(ctx, name); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.url("/v2/%s/manifests/%s", name, reference), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", mediaType)
+	resp, err := c.do(req, pushScope(name))
+	if err != nil {
+		return fmt.Errorf("failed to put manifest %s:%s: %w", name, reference, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to put manifest %s:%s: unexpected status %s", name, reference, resp.Status)
+	}
+	return nil
+}
+
+// ensureToken makes a request without a body to get a push token for the repository.
+func (c *registryClient) ensureToken(ctx context.Context, name string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.url("/v2/%s/blobs/sha256:0", name), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.do(req, pushScope(name))
+	if err != nil {
+		return fmt.Errorf("failed to authenticate to registry: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("failed to authenticate to registry: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (c *registryClient) hasBlob(ctx context.Context, name, digest string) (ok bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.url("/v2/%s/blobs/%s", name, digest), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.do(req, pushScope(name))
+	if err != nil {
+		return false, fmt.Errorf("failed to check blob %s: %w", digest, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+func (c *registryClient) getBlob(ctx context.Context, name, digest string) (r io.ReadCloser, size int64, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("/v2/%s/blobs/%s", name, digest), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.do(req, pullScope(name))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get blob %s: %w", digest, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("failed to get blob %s: unexpected status %s", digest, resp.Status)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// putBlob uploads a blob in a single request.
+func (c *registryClient) putBlob(ctx context.Context, name, digest string, r io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("/v2/%s/blobs/uploads/", name), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.do(req, pushScope(name))
+	if err != nil {
+		return fmt.Errorf("failed to start upload of blob %s: %w", digest, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to start upload of blob %s: unexpected status %s", digest, resp.Status)
+	}
+	location, err := req.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("failed to parse upload location: %w", err)
+	}
+	q := location.Query()
+	q.Set("digest", digest)
+	location.RawQuery = q.Encode()
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodPut, location.String(), r)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err = c.do(req, pushScope(name))
+	if err != nil {
+		return fmt.Errorf("failed to upload blob %s: %w", digest, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to upload blob %s: unexpected status %s", digest, resp.Status)
+	}
+	return nil
+}
+
+// copyImage copies the manifest with the given reference (a tag or digest), and everything it references, from the
+// source registry to the target.
+func copyImage(ctx context.Context, src *registryClient, srcName string, tgt *registryClient, tgtName, reference string) error {
+	body, mediaType, err := src.getManifest(ctx, srcName, reference)
+	if err != nil {
+		return err
+	}
+	var m manifest
+	if err = json.Unmarshal(body, &m); err != nil {
+		return fmt.Errorf("failed to parse manifest %s:%s: %w", srcName, reference, err)
+	}
+	// Indexes reference a manifest per platform.
+	for _, child := range m.Manifests {
+		if err = copyImage(ctx, src, srcName, tgt, tgtName, child.Digest); err != nil {
+			return err
+		}
+	}
+	blobs := m.Layers
+	if m.Config != nil {
+		blobs = append(blobs, *m.Config)
+	}
+	for _, blob := range blobs {
+		if err = copyBlob(ctx, src, srcName, tgt, tgtName, blob.Digest); err != nil {
+			return err
+		}
+	}
+	return tgt.putManifest(ctx, tgtName, reference, mediaType, body)
+}
+
+func copyBlob(ctx context.Context, src *registryClient, srcName string, tgt *registryClient, tgtName, digest string) error {
+	exists, err := tgt.hasBlob(ctx, tgtName, digest)
+	if err != nil || exists {
+		return err
+	}
+	r, size, err := src.getBlob(ctx, srcName, digest)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return tgt.putBlob(ctx, tgtName, digest, r, size)
+}
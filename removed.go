@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// removeDeletedRepos archives, or deletes, repos in the target org that are no longer present in the source. Only repos
+// created by the tool, which have a description starting with "Mirror of ", are removed, so that other repos in a
+// shared target org are left alone.
+func removeDeletedRepos(ctx context.Context, rc RepoClient, tgtURL string, srcRepos []Repo, m repoMap, archive bool) error {
+	u, err := url.Parse(tgtURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse url: %w", err)
+	}
+	org := strings.Split(strings.Trim(u.Path, "/"), "/")[0]
+	tgtRepos, err := rc.ListRepos(ctx, org, "org")
+	if err != nil {
+		return fmt.Errorf("failed to list target repos: %w", err)
+	}
+
+	// Repos are compared by the org/name they're copied to, since the repo map can rename them.
+	srcPaths := make(map[string]struct{}, len(srcRepos))
+	for _, r := range srcRepos {
+		tgt, err := m.TargetURL(r, tgtURL)
+		if err != nil {
+			return err
+		}
+		tu, err := url.Parse(tgt)
+		if err != nil {
+			return fmt.Errorf("failed to parse target url: %w", err)
+		}
+		srcPaths[strings.ToLower(strings.Trim(tu.Path, "/"))] = struct{}{}
+	}
+	for _, r := range tgtRepos {
+		if _, ok := srcPaths[strings.ToLower(org+"/"+r.Name)]; ok {
+			continue
+		}
+		if !strings.HasPrefix(r.Description, mirrorDescriptionPrefix) {
+			continue
+		}
+		if archive {
+			if r.Archived {
+				continue
+			}
+			fmt.Printf("Archiving %q, because it has been removed from the source...\n", r.URL)
+			if err = rc.EditRepo(ctx, org, r.Name, Repo{Archived: true}); err != nil {
+				return fmt.Errorf("failed to archive %q: %w", r.URL, err)
+			}
+			continue
+		}
+		fmt.Printf("Deleting %q, because it has been removed from the source...\n", r.URL)
+		if err = rc.DeleteRepo(ctx, org, r.Name); err != nil {
+			return fmt.Errorf("failed to delete %q: %w", r.URL, err)
+		}
+	}
+	return nil
+}
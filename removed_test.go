@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestRemoveDeletedRepos(t *testing.T) {
+	srcRepos := []Repo{
+		{Name: "kept", URL: "https://github.com/src/kept"},
+		{Name: "renamed", URL: "https://github.com/src/renamed"},
+	}
+	m := repoMap{"renamed": "tgt/new-name"}
+	newTarget := func() *mockRepoClient {
+		return newMockRepoClient("tgt",
+			Repo{Name: "kept", Description: "Mirror of https://github.com/src/kept"},
+			Repo{Name: "new-name", Description: "Mirror of https://github.com/src/renamed"},
+			Repo{Name: "removed", Description: "Mirror of https://github.com/src/removed [MIRROR]"},
+			Repo{Name: "already-archived", Description: "Mirror of https://github.com/src/already-archived", Archived: true},
+			Repo{Name: "unrelated", Description: "A repo that was created on the target"},
+			Repo{Name: "no-description"},
+		)
+	}
+
+	t.Run("archive", func(t *testing.T) {
+		rc := newTarget()
+		if err := removeDeletedRepos(context.Background(), rc, "https://github.example.com/tgt", srcRepos, m, true); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := []string{"tgt/removed"}; !slices.Equal(rc.Edited, want) {
+			t.Errorf("expected %v to be archived, got %v", want, rc.Edited)
+		}
+		if !rc.Repos["tgt/removed"].Archived {
+			t.Error("expected tgt/removed to be archived")
+		}
+		if len(rc.Deleted) > 0 {
+			t.Errorf("expected no repos to be deleted, got %v", rc.Deleted)
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		rc := newTarget()
+		if err := removeDeletedRepos(context.Background(), rc, "https://github.example.com/tgt", srcRepos, m, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := []string{"tgt/already-archived", "tgt/removed"}; !slices.Equal(rc.Deleted, want) {
+			t.Errorf("expected %v to be deleted, got %v", want, rc.Deleted)
+		}
+		if len(rc.Edited) > 0 {
+			t.Errorf("expected no repos to be archived, got %v", rc.Edited)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func parseBranchRename(v string) (from, to string, err error) {
+	from, to, ok := strings.Cut(v, "=")
+	if !ok || from == "" || to == "" {
+		return from, to, fmt.Errorf("expected old=new, e.g. master=main, got %q", v)
+	}
+	return from, to, nil
+}
+
+// renameLocalBranch renames the checked out branch of the local clone, so that it's pushed to the target under the
+// new name. It returns false if the checked out branch isn't called from.
+func renameLocalBranch(repo *git.Repository, from, to string) (renamed bool, err error) {
+	head, err := repo.Head()
+	if err != nil {
+		return false, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if head.Name() != plumbing.NewBranchReferenceName(from) {
+		return false, nil
+	}
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(to), head.Hash())); err != nil {
+		return false, fmt.Errorf("failed to create branch %q: %w", to, err)
+	}
+	if err = repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(to))); err != nil {
+		return false, fmt.Errorf("failed to update HEAD: %w", err)
+	}
+	if err = repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(from)); err != nil {
+		return false, fmt.Errorf("failed to remove branch %q: %w", from, err)
+	}
+	return true, nil
+}
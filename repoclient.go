@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// RepoClient is the API of a git hosting service, which decouples syncing from the service that hosts the repos.
+// Features that only Github supports, such as teams and rulesets, use the Github client directly.
+type RepoClient interface {
+	// ListRepos lists the repos of an owner. ownerType is org or user, and is ignored by services that only have one
+	// type of owner.
+	ListRepos(ctx context.Context, owner, ownerType string) ([]Repo, error)
+	// GetRepo returns the repo, and false if it doesn't exist.
+	GetRepo(ctx context.Context, owner, name string) (r Repo, ok bool, err error)
+	// CreateRepo creates an empty repo, returning errRepoExists if there's already a repo with the name.
+	CreateRepo(ctx context.Context, owner string, r Repo) error
+	// EditRepo updates the repo's settings with the non-zero fields of updates. Repos can be archived, but not
+	// unarchived.
+	EditRepo(ctx context.Context, owner, name string, updates Repo) error
+	// DeleteRepo permanently deletes the repo.
+	DeleteRepo(ctx context.Context, owner, name string) error
+}
+
+var errRepoExists = errors.New("repo already exists")
+
+// GitHubRepoClient is a RepoClient for Github and Github Enterprise Server.
+type GitHubRepoClient struct {
+	Client *github.Client
+	// Version is used to set the visibility of new repos in a way that the server supports.
+	Version serverVersion
+	// ObjectFormat is the hash algorithm of new repos, sha1 or sha256.
+	ObjectFormat string
+}
+
+func (c *GitHubRepoClient) ListRepos(ctx context.Context, owner, ownerType string) (repos []Repo, err error) {
+	r, err := listGitHubRepos(ctx, c.Client, owner, ownerType)
+	if err != nil {
+		return repos, err
+	}
+	for _, rr := range r {
+		repos = append(repos, repoFromGitHub(rr))
+	}
+	return repos, nil
+}
+
+func (c *GitHubRepoClient) GetRepo(ctx context.Context, owner, name string) (r Repo, ok bool, err error) {
+	rr, _, err := c.Client.Repositories.Get(ctx, owner, name)
+	if err != nil {
+		if isNotFound(err) {
+			return r, false, nil
+		}
+		return r, false, err
+	}
+	return repoFromGitHub(rr), true, nil
+}
+
+func (c *GitHubRepoClient) CreateRepo(ctx context.Context, owner string, r Repo) error {
+	newRepo := &github.Repository{
+		Name:        ptr(r.Name),
+		Description: ptr(r.Description),
+		// An initial commit in the target would cause the push to be rejected as a non-fast-forward update.
+		AutoInit: ptr(false),
+	}
+	setVisibility(newRepo, r.Visibility, c.Version)
+	_, err := createRepo(ctx, c.Client, owner, newRepo, c.ObjectFormat)
+	if err != nil && strings.Contains(err.Error(), "name already exists on this account") {
+		return fmt.Errorf("%w: %v", errRepoExists, err)
+	}
+	return err
+}
+
+func (c *GitHubRepoClient) EditRepo(ctx context.Context, owner, name string, updates Repo) error {
+	var r github.Repository
+	if updates.Description != "" {
+		r.Description = ptr(updates.Description)
+	}
+	if updates.Visibility != "" {
+		setVisibility(&r, updates.Visibility, c.Version)
+	}
+	if updates.DefaultBranch != "" {
+		r.DefaultBranch = ptr(updates.DefaultBranch)
+	}
+	if updates.Archived {
+		r.Archived = ptr(true)
+	}
+	_, _, err := c.Client.Repositories.Edit(ctx, owner, name, &r)
+	return err
+}
+
+func (c *GitHubRepoClient) DeleteRepo(ctx context.Context, owner, name string) error {
+	_, err := c.Client.Repositories.Delete(ctx, owner, name)
+	return err
+}
+
+func repoFromGitHub(rr *github.Repository) Repo {
+	return Repo{
+		Name: rr.GetName(),
+		// Use the canonical URL returned by the API, so that transferred repos are cloned from their new location.
+		URL:           rr.GetHTMLURL(),
+		Size:          rr.GetSize(),
+		Language:      rr.GetLanguage(),
+		UpdatedAt:     rr.GetUpdatedAt().Time,
+		CreatedAt:     rr.GetCreatedAt().Time,
+		Fork:          rr.GetFork(),
+		Archived:      rr.GetArchived(),
+		Description:   rr.GetDescription(),
+		Visibility:    rr.GetVisibility(),
+		DefaultBranch: rr.GetDefaultBranch(),
+		Topics:        rr.Topics,
+		HasWiki:       rr.GetHasWiki(),
+		Watchers:      rr.GetWatchersCount(),
+	}
+}
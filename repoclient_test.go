@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// newTestGitHubClient returns a Github client that sends its requests to the handler.
+func newTestGitHubClient(t *testing.T, handler http.Handler) *github.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client, err := github.NewClient(nil).WithEnterpriseURLs(srv.URL, srv.URL)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	return client
+}
+
+func TestGitHubRepoClientCreateRepoDisablesAutoInit(t *testing.T) {
+	for _, objectFormat := range []string{"sha1", "sha256"} {
+		t.Run(objectFormat, func(t *testing.T) {
+			var body map[string]any
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v3/orgs/org/repos", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("expected POST, got %s", r.Method)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request body: %v", err)
+				}
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"name":"repo"}`))
+			})
+			rc := &GitHubRepoClient{Client: newTestGitHubClient(t, mux), ObjectFormat: objectFormat}
+
+			err := rc.CreateRepo(context.Background(), "org", Repo{Name: "repo", Description: "Mirror of https://github.com/src/repo", Visibility: "private"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			autoInit, ok := body["auto_init"]
+			if !ok {
+				t.Fatalf("expected auto_init to be sent, got %v", body)
+			}
+			if autoInit != false {
+				t.Errorf("expected auto_init to be false, got %v", autoInit)
+			}
+			if body["name"] != "repo" {
+				t.Errorf("expected name %q, got %v", "repo", body["name"])
+			}
+		})
+	}
+}
+
+func TestGitHubRepoClientCreateRepoReturnsErrRepoExists(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/orgs/org/repos", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message":"Repository creation failed.","errors":[{"resource":"Repository","code":"custom","field":"name","message":"name already exists on this account"}]}`))
+	})
+	rc := &GitHubRepoClient{Client: newTestGitHubClient(t, mux)}
+
+	err := rc.CreateRepo(context.Background(), "org", Repo{Name: "repo"})
+	if !errors.Is(err, errRepoExists) {
+		t.Errorf("expected errRepoExists, got %v", err)
+	}
+}
+
+func TestGitHubRepoClientListReposUsesOwnerType(t *testing.T) {
+	tests := []struct {
+		ownerType string
+		path      string
+	}{
+		{ownerType: "org", path: "/api/v3/orgs/owner/repos"},
+		{ownerType: "user", path: "/api/v3/users/owner/repos"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ownerType, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					t.Errorf("expected a request to %q, got %q", tt.path, r.URL.Path)
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(`[{"name":"repo","html_url":"https://github.example.com/owner/repo","has_wiki":true,"watchers_count":2}]`))
+			})
+			rc := &GitHubRepoClient{Client: newTestGitHubClient(t, mux)}
+
+			repos, err := rc.ListRepos(context.Background(), "owner", tt.ownerType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(repos) != 1 || repos[0].Name != "repo" || !repos[0].HasWiki || repos[0].Watchers != 2 {
+				t.Errorf("unexpected repos: %+v", repos)
+			}
+		})
+	}
+}
+
+// mockRepoClient is an in-memory RepoClient that records the changes made to it.
+type mockRepoClient struct {
+	// Repos is keyed by owner/name.
+	Repos   map[string]Repo
+	Created []string
+	Edited  []string
+	Deleted []string
+	// CreateErr is returned by CreateRepo.
+	CreateErr error
+}
+
+func newMockRepoClient(owner string, repos ...Repo) *mockRepoClient {
+	m := &mockRepoClient{Repos: make(map[string]Repo)}
+	for _, r := range repos {
+		m.Repos[owner+"/"+r.Name] = r
+	}
+	return m
+}
+
+func (m *mockRepoClient) ListRepos(ctx context.Context, owner, ownerType string) (repos []Repo, err error) {
+	for key, r := range m.Repos {
+		if strings.HasPrefix(key, owner+"/") {
+			repos = append(repos, r)
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	return repos, nil
+}
+
+func (m *mockRepoClient) GetRepo(ctx context.Context, owner, name string) (r Repo, ok bool, err error) {
+	r, ok = m.Repos[owner+"/"+name]
+	return r, ok, nil
+}
+
+func (m *mockRepoClient) CreateRepo(ctx context.Context, owner string, r Repo) error {
+	if m.CreateErr != nil {
+		return m.CreateErr
+	}
+	if _, ok := m.Repos[owner+"/"+r.Name]; ok {
+		return errRepoExists
+	}
+	m.Repos[owner+"/"+r.Name] = r
+	m.Created = append(m.Created, owner+"/"+r.Name)
+	return nil
+}
+
+func (m *mockRepoClient) EditRepo(ctx context.Context, owner, name string, updates Repo) error {
+	key := owner + "/" + name
+	r, ok := m.Repos[key]
+	if !ok {
+		return fmt.Errorf("repo %q not found", key)
+	}
+	if updates.Description != "" {
+		r.Description = updates.Description
+	}
+	if updates.Archived {
+		r.Archived = true
+	}
+	m.Repos[key] = r
+	m.Edited = append(m.Edited, key)
+	return nil
+}
+
+func (m *mockRepoClient) DeleteRepo(ctx context.Context, owner, name string) error {
+	key := owner + "/" + name
+	if _, ok := m.Repos[key]; !ok {
+		return fmt.Errorf("repo %q not found", key)
+	}
+	delete(m.Repos, key)
+	m.Deleted = append(m.Deleted, key)
+	return nil
+}
+
+var _ RepoClient = &mockRepoClient{}
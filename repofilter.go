@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"slices"
+	"strings"
+	"time"
+)
+
+// repoFilter selects the source repos to copy, and is shared by syncs and the list sub-command, so that list prints
+// the repos that a sync would copy.
+type repoFilter struct {
+	CreatedAfter    string
+	CreatedBefore   string
+	ExcludeArchived bool
+	// RequireTopic is a comma separated list of topics.
+	RequireTopic string
+}
+
+func (f *repoFilter) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&f.CreatedAfter, "src-filter-created-after", "", "RFC 3339 time (e.g. 2024-01-01T00:00:00Z), only repos created after it are copied")
+	fs.StringVar(&f.CreatedBefore, "src-filter-created-before", "", "RFC 3339 time (e.g. 2024-04-01T00:00:00Z), only repos created before it are copied")
+	fs.StringVar(&f.RequireTopic, "src-require-topic", "", "Comma separated list of topics, e.g. mirror,public. Only source repos that have at least one of them are copied")
+	fs.BoolVar(&f.ExcludeArchived, "src-exclude-archived", false, "Set to true to skip archived source repos. By default, archived repos are copied like any other repo")
+}
+
+func (f repoFilter) Validate() (errors []string) {
+	after, afterErr := parseOptionalTime(f.CreatedAfter)
+	if afterErr != nil {
+		errors = append(errors, "src-filter-created-after: "+afterErr.Error())
+	}
+	before, beforeErr := parseOptionalTime(f.CreatedBefore)
+	if beforeErr != nil {
+		errors = append(errors, "src-filter-created-before: "+beforeErr.Error())
+	}
+	if afterErr == nil && beforeErr == nil && !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		errors = append(errors, "src-filter-created-after: must be before src-filter-created-before")
+	}
+	return errors
+}
+
+// Apply returns the repos that match the filter.
+func (f repoFilter) Apply(repos []Repo) []Repo {
+	if f.CreatedAfter != "" || f.CreatedBefore != "" {
+		// The flags are validated at startup.
+		after, _ := parseOptionalTime(f.CreatedAfter)
+		before, _ := parseOptionalTime(f.CreatedBefore)
+		repos = filterReposByCreatedAt(repos, after, before)
+	}
+	if f.ExcludeArchived {
+		repos = excludeArchivedRepos(repos)
+	}
+	if f.RequireTopic != "" {
+		repos = filterReposByTopic(repos, strings.Split(f.RequireTopic, ","))
+	}
+	return repos
+}
+
+// filterReposByCreatedAt returns the repos created between after and before. A zero time means no bound.
+func filterReposByCreatedAt(repos []Repo, after, before time.Time) (filtered []Repo) {
+	for _, r := range repos {
+		if !after.IsZero() && !r.CreatedAt.After(after) {
+			continue
+		}
+		if !before.IsZero() && !r.CreatedAt.Before(before) {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+func excludeArchivedRepos(repos []Repo) (filtered []Repo) {
+	for _, r := range repos {
+		if !r.Archived {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// filterReposByTopic returns the repos that have at least one of the topics.
+func filterReposByTopic(repos []Repo, topics []string) (filtered []Repo) {
+	for _, r := range repos {
+		for _, t := range topics {
+			if slices.Contains(r.Topics, strings.ToLower(strings.TrimSpace(t))) {
+				filtered = append(filtered, r)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// parseOptionalTime parses an RFC 3339 time, returning the zero time if s is empty.
+func parseOptionalTime(s string) (t time.Time, err error) {
+	if s == "" {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
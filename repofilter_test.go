@@ -0,0 +1,73 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRepoFilterApply(t *testing.T) {
+	repos := []Repo{
+		{Name: "old", CreatedAt: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), Topics: []string{"mirror"}},
+		{Name: "new", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "archived", CreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Archived: true, Topics: []string{"mirror"}},
+		{Name: "tagged", CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Topics: []string{"public"}},
+	}
+	tests := []struct {
+		name   string
+		filter repoFilter
+		want   []string
+	}{
+		{
+			name: "no filter",
+			want: []string{"old", "new", "archived", "tagged"},
+		},
+		{
+			name:   "created after",
+			filter: repoFilter{CreatedAfter: "2020-01-01T00:00:00Z"},
+			want:   []string{"new", "archived", "tagged"},
+		},
+		{
+			name:   "created between",
+			filter: repoFilter{CreatedAfter: "2020-01-01T00:00:00Z", CreatedBefore: "2024-02-15T00:00:00Z"},
+			want:   []string{"new", "archived"},
+		},
+		{
+			name:   "exclude archived",
+			filter: repoFilter{ExcludeArchived: true},
+			want:   []string{"old", "new", "tagged"},
+		},
+		{
+			name:   "require topic",
+			filter: repoFilter{RequireTopic: "mirror, Public"},
+			want:   []string{"old", "archived", "tagged"},
+		},
+		{
+			name:   "combined",
+			filter: repoFilter{CreatedAfter: "2020-01-01T00:00:00Z", ExcludeArchived: true, RequireTopic: "mirror"},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range tt.filter.Apply(repos) {
+				got = append(got, r.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRepoFilterValidate(t *testing.T) {
+	f := repoFilter{CreatedAfter: "2024-01-01T00:00:00Z", CreatedBefore: "2023-01-01T00:00:00Z"}
+	if errs := f.Validate(); len(errs) != 1 {
+		t.Errorf("expected an error for an empty range, got %v", errs)
+	}
+	f = repoFilter{CreatedAfter: "yesterday"}
+	if errs := f.Validate(); len(errs) != 1 {
+		t.Errorf("expected an error for an invalid time, got %v", errs)
+	}
+}
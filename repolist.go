@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// readRepoListFile reads the list of source repos from a JSON array of {"name":"...","url":"..."} objects,
+// or a CSV file with a header row containing name and url columns.
+func readRepoListFile(fileName string) (repos []Repo, err error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return repos, fmt.Errorf("failed to read repo list file: %w", err)
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		repos, err = parseRepoListJSON(data)
+	} else {
+		repos, err = parseRepoListCSV(data)
+	}
+	if err != nil {
+		return repos, fmt.Errorf("failed to parse repo list file %q: %w", fileName, err)
+	}
+	for i, r := range repos {
+		if r.Name == "" || r.URL == "" {
+			return repos, fmt.Errorf("repo list file %q: entry %d is missing a name or url", fileName, i+1)
+		}
+	}
+	return repos, nil
+}
+
+func parseRepoListJSON(data []byte) (repos []Repo, err error) {
+	err = json.Unmarshal(data, &repos)
+	return repos, err
+}
+
+func parseRepoListCSV(data []byte) (repos []Repo, err error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return repos, err
+	}
+	if len(records) == 0 {
+		return repos, nil
+	}
+	nameIndex, urlIndex := -1, -1
+	for i, header := range records[0] {
+		switch strings.ToLower(strings.TrimSpace(header)) {
+		case "name":
+			nameIndex = i
+		case "url":
+			urlIndex = i
+		}
+	}
+	if nameIndex < 0 || urlIndex < 0 {
+		return repos, fmt.Errorf("expected a header row containing name and url columns, got %v", records[0])
+	}
+	for _, record := range records[1:] {
+		repos = append(repos, Repo{
+			Name: strings.TrimSpace(record[nameIndex]),
+			URL:  strings.TrimSpace(record[urlIndex]),
+		})
+	}
+	return repos, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// repoMap maps source repo names to target repos in the form org/name, for repos that don't use the default target
+// org or name.
+type repoMap map[string]string
+
+// readRepoMapFile reads a JSON object of source repo names to target org/name, e.g. {"src-repo": "tgt-org/tgt-repo"}.
+func readRepoMapFile(fileName string) (m repoMap, err error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return m, fmt.Errorf("failed to read repo map file: %w", err)
+	}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("failed to parse repo map file %q: %w", fileName, err)
+	}
+	for src, tgt := range m {
+		if len(strings.Split(strings.Trim(tgt, "/"), "/")) != 2 {
+			return m, fmt.Errorf("repo map file %q: target of %q must be in the form org/name, got %q", fileName, src, tgt)
+		}
+	}
+	return m, nil
+}
+
+// TargetURL returns the URL of the target repo on the tgt host. Repos that aren't in the map are copied to the tgt
+// org with the same name.
+func (m repoMap) TargetURL(r Repo, tgt string) (updated string, err error) {
+	mapped, ok := m[r.Name]
+	if base, isWiki := strings.CutSuffix(r.Name, wikiSuffix); isWiki && !ok {
+		// Wikis follow the repo they belong to.
+		if mapped, ok = m[base]; ok {
+			mapped += wikiSuffix
+		}
+	}
+	if !ok {
+		return rewriteURL(r, tgt)
+	}
+	tgtURL, err := url.Parse(tgt)
+	if err != nil {
+		return updated, fmt.Errorf("failed to parse target URL: %w", err)
+	}
+	p := "/" + strings.Trim(mapped, "/")
+	tgtURL = &url.URL{
+		Scheme:  tgtURL.Scheme,
+		Host:    tgtURL.Host,
+		Path:    p,
+		RawPath: p,
+	}
+	return tgtURL.String(), nil
+}
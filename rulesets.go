@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// syncRulesets creates or updates the source repo's rulesets on the target. Team bypass actors are mapped to the team
+// with the same slug in the target org, and actors that can't be mapped, such as Github Apps, are dropped. Targets
+// that don't support rulesets get the source's branch protection rules instead.
+func syncRulesets(ctx context.Context, src, tgt repoRef, tgtVersion serverVersion) error {
+	if !tgtVersion.Supports(featureRulesets) {
+		fmt.Printf("Rulesets require %s, target is %v, copying branch protection instead.\n", requires(featureRulesets), tgtVersion)
+		return syncBranchProtection(ctx, src, tgt)
+	}
+	srcRulesets, _, err := src.Client.Repositories.GetAllRulesets(ctx, src.Owner, src.Name, false)
+	if err != nil {
+		return fmt.Errorf("failed to list source rulesets: %w", err)
+	}
+	tgtRulesets, _, err := tgt.Client.Repositories.GetAllRulesets(ctx, tgt.Owner, tgt.Name, false)
+	if err != nil {
+		return fmt.Errorf("failed to list target rulesets: %w", err)
+	}
+	existing := make(map[string]int64, len(tgtRulesets))
+	for _, rs := range tgtRulesets {
+		existing[rs.Name] = rs.GetID()
+	}
+	var teams *teamMapper
+	for _, summary := range srcRulesets {
+		// Rulesets inherited from the org are created in the target org, not the repo.
+		if summary.GetSourceType() == "Organization" {
+			continue
+		}
+		// Listing rulesets doesn't include their rules.
+		rs, _, err := src.Client.Repositories.GetRuleset(ctx, src.Owner, src.Name, summary.GetID(), false)
+		if err != nil {
+			return fmt.Errorf("failed to get ruleset %q: %w", summary.Name, err)
+		}
+		if teams == nil {
+			teams = &teamMapper{src: src, tgt: tgt}
+		}
+		ruleset, err := transformRuleset(ctx, rs, teams)
+		if err != nil {
+			return fmt.Errorf("failed to transform ruleset %q: %w", rs.Name, err)
+		}
+		if id, ok := existing[rs.Name]; ok {
+			if _, _, err = tgt.Client.Repositories.UpdateRuleset(ctx, tgt.Owner, tgt.Name, id, ruleset); err != nil {
+				return fmt.Errorf("failed to update ruleset %q: %w", rs.Name, err)
+			}
+			continue
+		}
+		if _, _, err = tgt.Client.Repositories.CreateRuleset(ctx, tgt.Owner, tgt.Name, ruleset); err != nil {
+			return fmt.Errorf("failed to create ruleset %q: %w", rs.Name, err)
+		}
+	}
+	return nil
+}
+
+// transformRuleset returns a copy of the ruleset that can be created on the target.
+func transformRuleset(ctx context.Context, rs *github.Ruleset, teams *teamMapper) (*github.Ruleset, error) {
+	ruleset := &github.Ruleset{
+		Name:        rs.Name,
+		Target:      rs.Target,
+		Enforcement: rs.Enforcement,
+		Conditions:  rs.Conditions,
+	}
+	for _, actor := range rs.BypassActors {
+		switch actor.GetActorType() {
+		case "Team":
+			id, err := teams.TargetID(ctx, actor.GetActorID())
+			if err != nil {
+				return nil, err
+			}
+			if id == 0 {
+				fmt.Printf("Warning: ruleset %q: skipping bypass team %d, because it isn't in the target org.\n", rs.Name, actor.GetActorID())
+				continue
+			}
+			ruleset.BypassActors = append(ruleset.BypassActors, &github.BypassActor{
+				ActorID:    github.Int64(id),
+				ActorType:  actor.ActorType,
+				BypassMode: actor.BypassMode,
+			})
+		case "Integration":
+			fmt.Printf("Warning: ruleset %q: skipping bypass app %d, because app IDs differ between servers.\n", rs.Name, actor.GetActorID())
+		default:
+			// Repository roles and org admins have the same IDs on every server.
+			ruleset.BypassActors = append(ruleset.BypassActors, actor)
+		}
+	}
+	for _, rule := range rs.Rules {
+		if rule.Type != "required_status_checks" || rule.Parameters == nil {
+			ruleset.Rules = append(ruleset.Rules, rule)
+			continue
+		}
+		var params github.RequiredStatusChecksRuleParameters
+		if err := json.Unmarshal(*rule.Parameters, &params); err != nil {
+			return nil, fmt.Errorf("failed to decode status check parameters: %w", err)
+		}
+		// Checks can be restricted to an app, but app IDs differ between servers.
+		for i := range params.RequiredStatusChecks {
+			params.RequiredStatusChecks[i].IntegrationID = nil
+		}
+		ruleset.Rules = append(ruleset.Rules, github.NewRequiredStatusChecksRule(&params))
+	}
+	return ruleset, nil
+}
+
+// teamMapper maps source team IDs to the IDs of the teams with the same slug in the target org. The teams are listed
+// the first time they're needed.
+type teamMapper struct {
+	src, tgt repoRef
+	ids      map[int64]int64
+}
+
+// TargetID returns the ID of the target team, or 0 if there isn't one.
+func (m *teamMapper) TargetID(ctx context.Context, srcID int64) (id int64, err error) {
+	if m.ids != nil {
+		return m.ids[srcID], nil
+	}
+	srcTeams, err := listTeams(ctx, m.src)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list source teams: %w", err)
+	}
+	tgtTeams, err := listTeams(ctx, m.tgt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list target teams: %w", err)
+	}
+	slugs := make(map[string]int64, len(tgtTeams))
+	for _, t := range tgtTeams {
+		slugs[t.GetSlug()] = t.GetID()
+	}
+	m.ids = make(map[int64]int64, len(srcTeams))
+	for _, t := range srcTeams {
+		if id, ok := slugs[t.GetSlug()]; ok {
+			m.ids[t.GetID()] = id
+		}
+	}
+	return m.ids[srcID], nil
+}
+
+func listTeams(ctx context.Context, r repoRef) (teams []*github.Team, err error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := r.Client.Teams.ListTeams(ctx, r.Owner, opts)
+		if err != nil {
+			return teams, err
+		}
+		teams = append(teams, page...)
+		if resp.NextPage == 0 {
+			return teams, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// syncBranchProtection copies the protection rules of the source's protected branches to the target. Push and
+// dismissal restrictions name users, teams and apps, which may not exist on the target, so they're not copied.
+func syncBranchProtection(ctx context.Context, src, tgt repoRef) error {
+	branches, err := listProtectedBranches(ctx, src)
+	if err != nil {
+		return fmt.Errorf("failed to list source protected branches: %w", err)
+	}
+	for _, b := range branches {
+		p, _, err := src.Client.Repositories.GetBranchProtection(ctx, src.Owner, src.Name, b.GetName())
+		if err != nil {
+			return fmt.Errorf("failed to get protection of branch %q: %w", b.GetName(), err)
+		}
+		req := &github.ProtectionRequest{
+			EnforceAdmins:        p.GetEnforceAdmins().Enabled,
+			RequireLinearHistory: github.Bool(p.GetRequireLinearHistory().Enabled),
+			AllowForcePushes:     github.Bool(p.GetAllowForcePushes().Enabled),
+			AllowDeletions:       github.Bool(p.GetAllowDeletions().Enabled),
+		}
+		if p.RequiredConversationResolution != nil {
+			req.RequiredConversationResolution = github.Bool(p.RequiredConversationResolution.Enabled)
+		}
+		if checks := p.RequiredStatusChecks; checks != nil {
+			req.RequiredStatusChecks = &github.RequiredStatusChecks{Strict: checks.Strict, Checks: []*github.RequiredStatusCheck{}}
+			for _, c := range checks.Checks {
+				req.RequiredStatusChecks.Checks = append(req.RequiredStatusChecks.Checks, &github.RequiredStatusCheck{Context: c.Context})
+			}
+		}
+		if reviews := p.RequiredPullRequestReviews; reviews != nil {
+			req.RequiredPullRequestReviews = &github.PullRequestReviewsEnforcementRequest{
+				DismissStaleReviews:          reviews.DismissStaleReviews,
+				RequireCodeOwnerReviews:      reviews.RequireCodeOwnerReviews,
+				RequiredApprovingReviewCount: reviews.RequiredApprovingReviewCount,
+				RequireLastPushApproval:      github.Bool(reviews.RequireLastPushApproval),
+			}
+		}
+		if _, _, err = tgt.Client.Repositories.UpdateBranchProtection(ctx, tgt.Owner, tgt.Name, b.GetName(), req); err != nil {
+			return fmt.Errorf("failed to protect branch %q: %w", b.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func listProtectedBranches(ctx context.Context, r repoRef) (branches []*github.Branch, err error) {
+	opts := &github.BranchListOptions{Protected: github.Bool(true), ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := r.Client.Repositories.ListBranches(ctx, r.Owner, r.Name, opts)
+		if err != nil {
+			return branches, err
+		}
+		branches = append(branches, page...)
+		if resp.NextPage == 0 {
+			return branches, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
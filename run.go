@@ -0,0 +1,606 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	SrcAccessToken       string
+	SrcURL               string
+	SrcURLSRV            string
+	SrcAuthBrowser       bool
+	SrcOAuthClientID     string
+	SrcOAuthClientSecret string
+	SrcAuthOIDC          bool
+	SrcOIDCExchangeURL   string
+	SrcOIDCAudience      string
+	SrcType              string
+	SrcRepoListFile      string
+	RepoMapFile          string
+	SrcOrgType           string
+	SrcGraphQL           bool
+	Filter               repoFilter
+	TgtAccessToken       string
+	TgtURL               string
+	AllowSameHost        bool
+	CreateTgtOrg         bool
+	TgtAdminUser         string
+	Copy                 copyOptions
+	Clients              clientOptions
+	ReportSlackURL       string
+	ReportAlways         bool
+	NotifyEmail          string
+	NotifyAlways         bool
+	SMTP                 smtpConfig
+	WaitForTarget        time.Duration
+	DeleteRemoved        bool
+	ArchiveOnDelete      bool
+	AllowHardDelete      bool
+	Every                time.Duration
+	Jitter               string
+	StateFile            string
+	Resume               bool
+	DontRecreateDeleted  bool
+	ProgressFile         string
+	Concurrency          int
+	SyncOrgMembership    bool
+	SyncWikis            bool
+	SyncPackages         bool
+	Log                  logOptions
+	// RepoMap is read from RepoMapFile at startup.
+	RepoMap repoMap
+}
+
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.SrcAccessToken, "src-token", "", "Personal access token for pulling from github.com")
+	fs.StringVar(&c.SrcURL, "src-url", "", "URL of source organization or repo, e.g. https://github.com/org")
+	fs.StringVar(&c.SrcURLSRV, "src-url-srv", "", "DNS SRV record name, e.g. _ghe._tcp.internal.corp.com, used to look up the host of src-url before each sync. Falls back to src-url if the lookup fails")
+	fs.BoolVar(&c.SrcAuthBrowser, "src-auth-browser", false, "Set to true to sign in to the src-url server with your browser instead of passing src-token. Requires src-oauth-client-id. The token is cached in your user config directory")
+	fs.StringVar(&c.SrcOAuthClientID, "src-oauth-client-id", "", "Client ID of the OAuth app used by src-auth-browser. The app's callback URL must be http://127.0.0.1/callback")
+	fs.StringVar(&c.SrcOAuthClientSecret, "src-oauth-client-secret", "", "Client secret of the OAuth app used by src-auth-browser, if the server requires it")
+	fs.BoolVar(&c.SrcAuthOIDC, "src-auth-oidc", false, "Set to true to get a source token in a Github Actions workflow by exchanging the job's OIDC token at src-oidc-exchange-url, instead of passing src-token. The workflow needs the id-token: write permission")
+	fs.StringVar(&c.SrcOIDCExchangeURL, "src-oidc-exchange-url", "", "URL of the token exchange service used by src-auth-oidc, which returns a Github App installation token for a valid OIDC token, e.g. https://octo-sts.dev/sts/exchange?scope=org&identity=copy")
+	fs.StringVar(&c.SrcOIDCAudience, "src-oidc-audience", "", "Audience of the OIDC token requested by src-auth-oidc, defaults to the audience set by Github")
+	fs.StringVar(&c.SrcType, "src-type", "github", "Type of the source, can be github or bitbucket. When bitbucket, src-url is the URL of a Bitbucket Cloud workspace, e.g. https://bitbucket.org/workspace")
+	fs.StringVar(&c.SrcOrgType, "src-org-type", "auto", "Type of the src-url owner, can be auto, org or user. Setting org or user skips the API call used to detect the type")
+	fs.BoolVar(&c.SrcGraphQL, "src-graphql", false, "Set to true to list the repos of a Github src-url organization or user with the GraphQL API, which makes fewer API calls than the REST API")
+	c.Filter.RegisterFlags(fs)
+	fs.StringVar(&c.SrcRepoListFile, "src-repo-list-file", "", "Path to a JSON or CSV file containing the names and URLs of the source repos to copy, used instead of listing the repos at src-url")
+	fs.StringVar(&c.RepoMapFile, "repo-map-file", "", "Path to a JSON file mapping source repo names to target repos in the form org/name, e.g. {\"src-repo\": \"tgt-org/tgt-repo\"}. Repos that aren't in the file are copied to tgt-url with the same name")
+	fs.StringVar(&c.TgtAccessToken, "tgt-token", "", "Personal access token for pushing to Github Enterprise")
+	fs.StringVar(&c.TgtURL, "tgt-url", "", "URL of target org to push to, e.g. https://github.enterprise.com/org")
+	fs.BoolVar(&c.AllowSameHost, "allow-same-host", false, "Set to true to allow src-url and tgt-url to be the same org on the same host. By default this is an error, because repos would be force-pushed to themselves")
+	fs.BoolVar(&c.CreateTgtOrg, "create-tgt-org", false, "Set to true to create the tgt-url org before copying if it doesn't exist. Requires a GHES target and a site admin tgt-token")
+	fs.StringVar(&c.TgtAdminUser, "tgt-admin-user", "", "Login of the user to make admin of the org created by create-tgt-org")
+	c.Copy.RegisterFlags(fs)
+	c.Log.RegisterFlags(fs)
+	fs.IntVar(&c.Concurrency, "concurrency", 1, "Number of repos to copy at the same time")
+	c.Clients.RegisterFlags(fs)
+	fs.StringVar(&c.ReportSlackURL, "report-slack-url", "", "Slack incoming webhook URL to post a summary to after each sync, only posts when a repo fails to copy unless report-always is set")
+	fs.BoolVar(&c.ReportAlways, "report-always", false, "Set to true to post a summary after every sync, not just syncs with failures")
+	fs.StringVar(&c.NotifyEmail, "notify-email", "", "Comma separated email addresses to send a summary to after each sync, only sent when a repo fails to copy unless notify-always is set")
+	fs.BoolVar(&c.NotifyAlways, "notify-always", false, "Set to true to send a summary email after every sync, not just syncs with failures")
+	fs.StringVar(&c.SMTP.Host, "smtp-host", "", "Host of the SMTP server used to send notify-email")
+	fs.IntVar(&c.SMTP.Port, "smtp-port", 587, "Port of the SMTP server. STARTTLS is used if the server supports it")
+	fs.StringVar(&c.SMTP.User, "smtp-user", "", "Username for the SMTP server, if it requires authentication")
+	fs.StringVar(&c.SMTP.Password, "smtp-password", "", "Password for the SMTP server")
+	fs.StringVar(&c.SMTP.From, "smtp-from", "", "Sender address of notify-email, defaults to smtp-user")
+	fs.BoolVar(&c.SMTP.TLSSkipVerify, "smtp-tls-skip-verify", false, "Set to true to skip verification of the SMTP server's certificate, e.g. for internal servers with self-signed certificates")
+	fs.DurationVar(&c.WaitForTarget, "wait-for-target", 0, "If set, wait up to this long for the tgt-url server's API to respond before starting, e.g. while GHES is booting")
+	fs.BoolVar(&c.DeleteRemoved, "delete-removed", false, "Set to true to remove repos from the target org when they no longer exist in the source org")
+	fs.BoolVar(&c.ArchiveOnDelete, "archive-on-delete", true, "When delete-removed is set, archive removed repos instead of deleting them. Set to false, with allow-hard-delete, to permanently delete them")
+	fs.BoolVar(&c.AllowHardDelete, "allow-hard-delete", false, "Set to true to allow delete-removed to permanently delete repos when archive-on-delete is false")
+	fs.DurationVar(&c.Every, "every", time.Duration(0), "If set, keep running, and sync again after a delay.")
+	fs.BoolVar(&c.SyncOrgMembership, "sync-org-membership", false, "Set to true to give members of the source org the same role in the target org after each sync. Requires a GHES 3.6+ target with users provisioned under the same logins, e.g. via SCIM")
+	fs.BoolVar(&c.SyncWikis, "sync-wikis", false, "Set to true to also copy the wikis of Github source repos. Only repos with a wiki enabled and at least one watcher are checked, since the API doesn't report whether a wiki has pages")
+	fs.BoolVar(&c.SyncPackages, "sync-packages", false, "Set to true to copy the tagged container images of the source org's Github Packages that belong to the copied repos to the target's container registry. Other package types aren't supported")
+	fs.StringVar(&c.StateFile, "state-file", "", "Path to a JSON file used to record the time each repo was last synced")
+	fs.BoolVar(&c.Resume, "resume", false, "Set to true to skip repos that were copied by an interrupted sync, and copy the rest. Requires state-file")
+	fs.BoolVar(&c.DontRecreateDeleted, "dont-recreate-deleted", false, "Set to true to skip repos that were copied before, but have since been deleted from the target, instead of recreating them. Skipped repos are recorded in the state file. Requires state-file")
+	fs.StringVar(&c.ProgressFile, "progress-file", "", "Path to a JSON file that is overwritten every second during a sync with the number of repos completed, the repos in progress and failed, and the estimated seconds remaining")
+	fs.StringVar(&c.Jitter, "jitter", "10%", "Maximum random delay added to each -every wait, to prevent instances from syncing at the same time. Either a duration (e.g. 30s), or a percentage of -every (e.g. 10%)")
+}
+
+func (c Config) Validate() (errors []string) {
+	if c.SrcAccessToken == "" && !c.SrcAuthBrowser && !c.SrcAuthOIDC {
+		errors = append(errors, "Missing src-token, src-auth-browser or src-auth-oidc flag")
+	}
+	if c.SrcAuthBrowser && c.SrcAuthOIDC {
+		errors = append(errors, "src-auth-oidc: can't be used with src-auth-browser")
+	}
+	if c.SrcAuthOIDC {
+		if c.SrcOIDCExchangeURL == "" {
+			errors = append(errors, "src-auth-oidc: src-oidc-exchange-url is required")
+		}
+		if c.SrcType != "github" {
+			errors = append(errors, "src-auth-oidc: only supported with a Github source")
+		}
+	}
+	if c.SrcAuthBrowser {
+		if c.SrcOAuthClientID == "" {
+			errors = append(errors, "src-auth-browser: src-oauth-client-id is required")
+		}
+		if c.SrcType != "github" || c.SrcURL == "" {
+			errors = append(errors, "src-auth-browser: only supported with a Github src-url")
+		}
+	}
+	if c.SrcURL == "" && c.SrcRepoListFile == "" {
+		errors = append(errors, "Missing src-url or src-repo-list-file flag")
+	}
+	if c.TgtAccessToken == "" {
+		errors = append(errors, "Missing tgt-token flag")
+	}
+	if c.TgtURL == "" {
+		errors = append(errors, "Missing tgt-url flag")
+	}
+	if msg := isOneOf(c.SrcType, "github", "bitbucket"); msg != "" {
+		errors = append(errors, "src-type: "+msg)
+	}
+	if msg := isOneOf(c.SrcOrgType, "auto", "org", "user"); msg != "" {
+		errors = append(errors, "src-org-type: "+msg)
+	}
+	errors = append(errors, c.Copy.Validate()...)
+	errors = append(errors, c.Log.Validate()...)
+	errors = append(errors, c.Filter.Validate()...)
+	errors = append(errors, c.Clients.Validate()...)
+	if (c.Filter.CreatedAfter != "" || c.Filter.CreatedBefore != "") && c.SrcRepoListFile != "" {
+		errors = append(errors, "src-filter-created-after, src-filter-created-before: not supported with src-repo-list-file, which doesn't include creation times")
+	}
+	if c.Filter.RequireTopic != "" && (c.SrcType != "github" || c.SrcRepoListFile != "" || !isOrgURL(c.SrcURL)) {
+		errors = append(errors, "src-require-topic: only supported when src-url is a Github organization or user")
+	}
+	if c.SrcType != "github" && c.Copy.SyncDeployKeys {
+		errors = append(errors, "sync-deploy-keys: only supported when src-type is github")
+	}
+	if c.SrcType != "github" && c.Copy.SyncSecurity {
+		errors = append(errors, "sync-security-settings: only supported when src-type is github")
+	}
+	if c.SrcType != "github" && c.Copy.LabelSync {
+		errors = append(errors, "label-sync: only supported when src-type is github")
+	}
+	if c.SrcType != "github" && c.Copy.SyncRulesets {
+		errors = append(errors, "sync-rulesets: only supported when src-type is github")
+	}
+	if c.RepoMapFile != "" {
+		if _, err := readRepoMapFile(c.RepoMapFile); err != nil {
+			errors = append(errors, "repo-map-file: "+err.Error())
+		}
+	}
+	if c.SrcURLSRV != "" && c.SrcURL == "" {
+		errors = append(errors, "src-url-srv: src-url is required, to provide the organization or repo path and a fallback host")
+	}
+	if c.SrcGraphQL && (c.SrcType != "github" || c.SrcRepoListFile != "") {
+		errors = append(errors, "src-graphql: only supported when listing the repos of a Github src-url")
+	}
+	if c.SyncWikis && (c.SrcType != "github" || c.SrcRepoListFile != "") {
+		errors = append(errors, "sync-wikis: only supported when listing the repos of a Github src-url")
+	}
+	if c.SyncPackages && (c.SrcType != "github" || !isOrgURL(c.SrcURL)) {
+		errors = append(errors, "sync-packages: src-url must be a Github organization URL")
+	}
+	if c.SyncOrgMembership && (c.SrcType != "github" || !isOrgURL(c.SrcURL)) {
+		errors = append(errors, "sync-org-membership: src-url must be a Github organization URL")
+	}
+	if c.SrcURL != "" && c.TgtURL != "" && !c.AllowSameHost && sameHostAndOrg(c.SrcURL, c.TgtURL) {
+		errors = append(errors, "src-url and tgt-url are the same org on the same host, so repos would be pushed to themselves. Set allow-same-host to override")
+	}
+	if c.Copy.TgtType == "azure-devops" {
+		githubOnly := []struct {
+			name string
+			set  bool
+		}{
+			{"create-tgt-org", c.CreateTgtOrg},
+			{"wait-for-target", c.WaitForTarget > 0},
+			{"repo-map-file", c.RepoMapFile != ""},
+			{"delete-removed", c.DeleteRemoved},
+			{"sync-wikis", c.SyncWikis},
+			{"sync-packages", c.SyncPackages},
+			{"sync-org-membership", c.SyncOrgMembership},
+		}
+		for _, f := range githubOnly {
+			if f.set {
+				errors = append(errors, f.name+": only supported when tgt-type is github")
+			}
+		}
+		if _, err := azureDevOpsRepoURL(c.TgtURL, "repo"); c.TgtURL != "" && err != nil {
+			errors = append(errors, "tgt-url: "+err.Error())
+		}
+	}
+	if c.CreateTgtOrg && c.TgtAdminUser == "" {
+		errors = append(errors, "create-tgt-org: tgt-admin-user is required")
+	}
+	if c.DeleteRemoved && c.SrcRepoListFile == "" && c.SrcType == "github" && !isOrgURL(c.SrcURL) {
+		errors = append(errors, "delete-removed: src-url must be an organization URL")
+	}
+	if c.DeleteRemoved && !c.ArchiveOnDelete && !c.AllowHardDelete {
+		errors = append(errors, "archive-on-delete: allow-hard-delete must be set to permanently delete repos")
+	}
+	if c.NotifyEmail != "" {
+		if c.SMTP.Host == "" {
+			errors = append(errors, "notify-email: smtp-host is required")
+		}
+		if c.SMTP.From == "" && c.SMTP.User == "" {
+			errors = append(errors, "notify-email: smtp-from or smtp-user is required")
+		}
+	}
+	if c.Resume && c.StateFile == "" {
+		errors = append(errors, "resume: state-file is required")
+	}
+	if c.DontRecreateDeleted && c.StateFile == "" {
+		errors = append(errors, "dont-recreate-deleted: state-file is required")
+	}
+	if c.Concurrency < 1 {
+		errors = append(errors, "concurrency: must be at least 1")
+	}
+	if _, err := parseJitter(c.Jitter, c.Every); err != nil {
+		errors = append(errors, "jitter: "+err.Error())
+	}
+	return errors
+}
+
+// SyncResult is the outcome of a sync. Repos that failed to copy are recorded in Failed, while failures that stop
+// the whole sync (e.g. failing to list the source repos) are returned as errors.
+type SyncResult struct {
+	Total  int
+	Copied int
+	// NewRepos, UpdatedRepos and UnchangedRepos are the names of the copied repos, by the change made to the target.
+	NewRepos       []string
+	UpdatedRepos   []string
+	UnchangedRepos []string
+	Failed         []failedRepo
+	Duration       time.Duration
+}
+
+func (r SyncResult) HasFailures() bool {
+	return len(r.Failed) > 0
+}
+
+type failedRepo struct {
+	Name   string
+	TgtURL string
+	Err    error
+}
+
+// run syncs the repos, and if cfg.Every is set, keeps syncing until the context is cancelled. The result of the
+// most recent sync is returned.
+func run(ctx context.Context, cfg Config) (result SyncResult, err error) {
+	jitter, err := parseJitter(cfg.Jitter, cfg.Every)
+	if err != nil {
+		return result, fmt.Errorf("invalid jitter: %w", err)
+	}
+
+	clients, err := cfg.Clients.NewClientFactory()
+	if err != nil {
+		return result, err
+	}
+
+	if cfg.WaitForTarget > 0 {
+		if err = waitForHost(ctx, clients.transport, cfg.TgtURL, cfg.WaitForTarget); err != nil {
+			return result, err
+		}
+	}
+
+	if cfg.Clients.ValidateConnectivity {
+		srcURL := cfg.SrcURL
+		if cfg.SrcURLSRV != "" {
+			srcURL = resolveSRVURL(cfg.SrcURLSRV, cfg.SrcURL)
+		}
+		if err = cfg.Clients.CheckConnectivity(srcURL, cfg.TgtURL); err != nil {
+			return result, err
+		}
+	}
+
+	if cfg.SrcAuthBrowser {
+		if cfg.SrcAccessToken, err = browserAuth(ctx, clients, cfg.SrcURL, cfg.SrcOAuthClientID, cfg.SrcOAuthClientSecret); err != nil {
+			return result, fmt.Errorf("failed to sign in to source: %w", err)
+		}
+	}
+	if cfg.SrcAuthOIDC {
+		if cfg.SrcAccessToken, err = oidcAuth(ctx, clients, cfg.SrcOIDCExchangeURL, cfg.SrcOIDCAudience); err != nil {
+			return result, fmt.Errorf("failed to get source token with OIDC: %w", err)
+		}
+	}
+
+	// Fail fast, rather than failing to copy every repo. Installation tokens from src-auth-oidc can't get the
+	// authenticated user, so aren't checked.
+	if cfg.SrcType == "github" && cfg.SrcURL != "" && !cfg.SrcAuthOIDC {
+		srcURL := cfg.SrcURL
+		if cfg.SrcURLSRV != "" {
+			srcURL = resolveSRVURL(cfg.SrcURLSRV, cfg.SrcURL)
+		}
+		if err = checkTokenScopes(ctx, clients, srcURL, cfg.SrcAccessToken, "src-token", "repo"); err != nil {
+			return result, err
+		}
+	}
+	if cfg.Copy.TgtType == "github" {
+		if cfg.Copy.TgtVersion, err = checkGitHubTarget(ctx, clients, cfg.TgtURL, cfg.TgtAccessToken); err != nil {
+			return result, err
+		}
+	}
+
+	// The throttle is shared by the concurrent copies, so that the interval applies to all of their pushes.
+	cfg.Copy.PushThrottle = newPushThrottle(cfg.Copy.MinPushInterval)
+
+	if cfg.CreateTgtOrg {
+		if !cfg.Copy.TgtVersion.Enterprise {
+			return result, fmt.Errorf("create-tgt-org is only supported when the target is Github Enterprise Server")
+		}
+		if err = createTgtOrgIfMissing(ctx, clients, cfg.TgtURL, cfg.TgtAccessToken, cfg.TgtAdminUser); err != nil {
+			return result, err
+		}
+	}
+
+	if cfg.RepoMapFile != "" {
+		if cfg.RepoMap, err = readRepoMapFile(cfg.RepoMapFile); err != nil {
+			return result, err
+		}
+	}
+
+	state := NewState()
+	if cfg.StateFile != "" {
+		state = LoadStateFile(cfg.StateFile)
+	}
+
+	for {
+		clients.stats.Reset()
+		if result, err = syncRepos(ctx, cfg, clients, state); err != nil {
+			return result, err
+		}
+		if cfg.StateFile != "" {
+			if err = state.Save(cfg.StateFile); err != nil {
+				fmt.Printf("Failed to save state file: %v\n", err)
+			}
+		}
+		fmt.Printf("Copied %d of %d repos in %v.\n", result.Copied, result.Total, result.Duration)
+		fmt.Printf("New: %d | Updated: %d | Unchanged: %d | Failed: %d\n", len(result.NewRepos), len(result.UpdatedRepos), len(result.UnchangedRepos), len(result.Failed))
+		clients.stats.Print(cfg.Every)
+
+		if cfg.ReportSlackURL != "" && (result.HasFailures() || cfg.ReportAlways) {
+			if err = postSlackReport(ctx, cfg.ReportSlackURL, result); err != nil {
+				fmt.Printf("Failed to post Slack report: %v\n", err)
+			}
+		}
+		if cfg.NotifyEmail != "" && (result.HasFailures() || cfg.NotifyAlways) {
+			if err = sendEmailReport(ctx, cfg.SMTP, cfg.NotifyEmail, result); err != nil {
+				fmt.Printf("Failed to send email report: %v\n", err)
+			}
+		}
+
+		if cfg.Every == time.Duration(0) {
+			return result, nil
+		}
+		wait := cfg.Every + randomJitter(jitter)
+		fmt.Printf("Process complete. Running again in %v.\n", wait)
+		select {
+		case <-ctx.Done():
+			fmt.Printf("Context closed.\n")
+			return result, nil
+		case <-time.After(wait):
+			fmt.Printf("Wait complete.\n")
+		}
+	}
+}
+
+// checkGitHubTarget checks that the tgt-token can create repos, and detects the version of the target server.
+func checkGitHubTarget(ctx context.Context, clients *clientFactory, tgtURL, token string) (v serverVersion, err error) {
+	if err = checkTokenScopes(ctx, clients, tgtURL, token, "tgt-token", "repo", "admin:org"); err != nil {
+		return v, err
+	}
+	if v, err = detectServerVersion(ctx, clients, tgtURL, token); err != nil {
+		return v, fmt.Errorf("failed to detect target server version: %w", err)
+	}
+	fmt.Printf("Target server version: %v\n", v)
+	return v, nil
+}
+
+func syncRepos(ctx context.Context, cfg Config, clients *clientFactory, state *State) (result SyncResult, err error) {
+	start := time.Now()
+	// Re-resolve the SRV record each sync, in case it has changed.
+	srcURL := cfg.SrcURL
+	if cfg.SrcURLSRV != "" {
+		srcURL = resolveSRVURL(cfg.SrcURLSRV, cfg.SrcURL)
+	}
+	var repos []Repo
+	if cfg.SrcRepoListFile != "" {
+		fmt.Printf("Reading repos from file: %v\n", cfg.SrcRepoListFile)
+		repos, err = readRepoListFile(cfg.SrcRepoListFile)
+	} else if cfg.SrcType == "bitbucket" {
+		fmt.Printf("Listing Bitbucket repos for URL: %v\n", srcURL)
+		repos, err = listBitbucketRepos(ctx, clients, srcURL, cfg.SrcAccessToken)
+	} else if cfg.SrcGraphQL && isOrgURL(srcURL) {
+		fmt.Printf("Listing repos for URL with GraphQL: %v\n", srcURL)
+		repos, err = listReposGraphQL(ctx, clients, srcURL, cfg.SrcAccessToken, cfg.SyncWikis)
+	} else {
+		fmt.Printf("Listing repos for URL: %v\n", srcURL)
+		repos, err = listRepos(ctx, clients, srcURL, cfg.SrcAccessToken, cfg.SrcOrgType, cfg.SyncWikis)
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to list repos: %w", err)
+	}
+
+	// Repos that are filtered out still exist in the source, so mustn't be removed from the target.
+	srcRepos := repos
+	repos = cfg.Filter.Apply(repos)
+
+	// Repos synced since the start of an interrupted sync don't need copying again.
+	if cfg.Resume && state.SyncStartedAt.After(state.SyncCompletedAt) {
+		var remaining []Repo
+		for _, r := range repos {
+			if lastSyncedAt := state.Repos[r.URL].LastSyncedAt; !lastSyncedAt.Before(state.SyncStartedAt) {
+				fmt.Printf("Skipping %q, because the state file shows it was synced at %v.\n", r.URL, lastSyncedAt.Format(time.RFC3339))
+				continue
+			}
+			remaining = append(remaining, r)
+		}
+		fmt.Printf("Resuming the sync started at %v, skipping %d repos that have already been synced.\n", state.SyncStartedAt.Format(time.RFC3339), len(repos)-len(remaining))
+		repos = remaining
+	} else {
+		state.SyncStartedAt = start
+	}
+
+	// Repos that were deleted from the target after being copied are only copied again if they're removed from the
+	// state file.
+	var copiedBefore map[string]bool
+	if cfg.DontRecreateDeleted {
+		var remaining []Repo
+		copiedBefore = make(map[string]bool, len(state.Repos))
+		for _, r := range repos {
+			if _, ok := state.Deleted[r.URL]; ok {
+				fmt.Printf("Warning: skipping %q, because it was deleted from the target after being copied.\n", r.URL)
+				continue
+			}
+			_, copiedBefore[r.URL] = state.Repos[r.URL]
+			remaining = append(remaining, r)
+		}
+		repos = remaining
+	}
+
+	fmt.Printf("Copying %d repos.\n", len(repos))
+
+	result.Total = len(repos)
+	// A fixed number of workers copy the repos, so that the number of goroutines and clones in progress is bounded
+	// by the concurrency setting rather than the number of repos.
+	progress := newSyncProgress(len(repos), cfg.Concurrency)
+	if cfg.ProgressFile != "" {
+		progressCtx, stopProgress := context.WithCancel(ctx)
+		progressDone := make(chan struct{})
+		go func() {
+			defer close(progressDone)
+			progress.WriteFileEvery(progressCtx, cfg.ProgressFile, time.Second)
+		}()
+		// Wait for the final write, so that the file shows the sync as complete.
+		defer func() {
+			stopProgress()
+			<-progressDone
+		}()
+	}
+	// Each job is a repo followed by its wiki, which is copied by the same worker once the repo has been, because the
+	// wiki can't be enabled until the target repo exists.
+	jobs := make(chan []Repo)
+	results := make(chan repoResult, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				for _, repo := range job {
+					progress.Start(repo.Name)
+					results <- copyRepo(ctx, cfg, clients, repo, copiedBefore[repo.URL])
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, job := range groupWikis(repos) {
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	for r := range results {
+		counter := progress.Finish(r.Repo.Name, r.Duration, r.Err)
+		fmt.Printf("%s Finished %q in %v.\n", counter, r.Repo.Name, r.Duration.Round(time.Second))
+		if r.Err != nil {
+			fmt.Printf("Failed to copy %q: %v\n", r.Repo.URL, r.Err)
+			logCopyError(r.Err)
+			result.Failed = append(result.Failed, failedRepo{Name: r.Repo.Name, TgtURL: r.TgtURL, Err: r.Err})
+			continue
+		}
+		if r.Status == copyDeleted {
+			delete(state.Repos, r.Repo.URL)
+			state.Deleted[r.Repo.URL] = time.Now()
+			if cfg.StateFile != "" {
+				if err = state.Save(cfg.StateFile); err != nil {
+					fmt.Printf("Failed to save state file: %v\n", err)
+				}
+			}
+			continue
+		}
+		result.Copied++
+		switch r.Status {
+		case copyNew:
+			result.NewRepos = append(result.NewRepos, r.Repo.Name)
+		case copyUpdated:
+			result.UpdatedRepos = append(result.UpdatedRepos, r.Repo.Name)
+		case copyUnchanged:
+			result.UnchangedRepos = append(result.UnchangedRepos, r.Repo.Name)
+		}
+		state.Repos[r.Repo.URL] = RepoState{LastSyncedAt: time.Now()}
+		// Save after each repo, so that an interrupted sync can be resumed.
+		if cfg.StateFile != "" {
+			if err = state.Save(cfg.StateFile); err != nil {
+				fmt.Printf("Failed to save state file: %v\n", err)
+			}
+		}
+	}
+	if ctx.Err() == nil {
+		state.SyncCompletedAt = time.Now()
+	}
+	if cfg.DeleteRemoved {
+		rc, err := clients.NewGitHubRepoClient(cfg.TgtURL, cfg.TgtAccessToken)
+		if err == nil {
+			err = removeDeletedRepos(ctx, rc, cfg.TgtURL, srcRepos, cfg.RepoMap, cfg.ArchiveOnDelete)
+		}
+		if err != nil {
+			fmt.Printf("Failed to remove deleted repos: %v\n", err)
+		}
+	}
+	if cfg.SyncPackages {
+		if err = syncPackages(ctx, clients, srcURL, cfg.SrcAccessToken, cfg.TgtURL, cfg.TgtAccessToken, repos); err != nil {
+			fmt.Printf("Failed to sync packages: %v\n", err)
+		}
+	}
+	if cfg.SyncOrgMembership {
+		// SCIM provisioning keeps logins consistent with the source.
+		if !cfg.Copy.TgtVersion.Enterprise || !cfg.Copy.TgtVersion.Supports(featureSCIM) {
+			fmt.Printf("Warning: skipping org membership sync, because it requires a %s target, target is %v\n", requires(featureSCIM), cfg.Copy.TgtVersion)
+		} else if err = syncOrgMembership(ctx, clients, srcURL, cfg.SrcAccessToken, cfg.TgtURL, cfg.TgtAccessToken); err != nil {
+			fmt.Printf("Failed to sync org membership: %v\n", err)
+		}
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+type repoResult struct {
+	Repo   Repo
+	TgtURL string
+	Status copyStatus
+	Err    error
+	// Duration is the time taken to clone and push the repo.
+	Duration time.Duration
+}
+
+// copyRepo copies the repo to the target. If copiedBefore is set, and dont-recreate-deleted is enabled, a missing
+// target isn't recreated.
+func copyRepo(ctx context.Context, cfg Config, clients *clientFactory, repo Repo, copiedBefore bool) (r repoResult) {
+	r.Repo = repo
+	if cfg.Copy.TgtType == "azure-devops" {
+		r.TgtURL, r.Err = azureDevOpsRepoURL(cfg.TgtURL, repo.Name)
+	} else {
+		r.TgtURL, r.Err = cfg.RepoMap.TargetURL(repo, cfg.TgtURL)
+	}
+	if r.Err != nil {
+		r.Err = fmt.Errorf("failed to rewrite URL: %w", r.Err)
+		return r
+	}
+	fmt.Printf("Copying %q to %q...\n", repo.URL, r.TgtURL)
+	start := time.Now()
+	defer func() { r.Duration = time.Since(start) }()
+	opts := cfg.Copy
+	opts.DontCreate = cfg.DontRecreateDeleted && copiedBefore
+	r.Status, r.Err = copy(ctx, clients, cfg.SrcAccessToken, repo.URL, cfg.TgtAccessToken, r.TgtURL, opts)
+	return r
+}
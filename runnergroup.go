@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// addRepoToRunnerGroup gives the repo access to an org runner group, identified by name or ID. The runner group must
+// be restricted to selected repositories.
+func addRepoToRunnerGroup(ctx context.Context, client *github.Client, org, group string, repoID int64) error {
+	groupID, err := findRunnerGroupID(ctx, client, org, group)
+	if err != nil {
+		return err
+	}
+	if _, err = client.Actions.AddRepositoryAccessRunnerGroup(ctx, org, groupID, repoID); err != nil {
+		return fmt.Errorf("failed to add repo to runner group %q: %w", group, err)
+	}
+	return nil
+}
+
+func findRunnerGroupID(ctx context.Context, client *github.Client, org, group string) (id int64, err error) {
+	if id, err = strconv.ParseInt(group, 10, 64); err == nil {
+		return id, nil
+	}
+	opts := &github.ListOrgRunnerGroupOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		groups, resp, err := client.Actions.ListOrganizationRunnerGroups(ctx, org, opts)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list runner groups: %w", err)
+		}
+		for _, g := range groups.RunnerGroups {
+			if g.GetName() == group {
+				return g.GetID(), nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return 0, fmt.Errorf("runner group %q not found in %q", group, org)
+		}
+		opts.Page = resp.NextPage
+	}
+}
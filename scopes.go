@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// impliedScopes are the scopes granted by a parent scope.
+var impliedScopes = map[string][]string{
+	"repo":      {"repo:status", "repo_deployment", "public_repo", "repo:invite", "security_events"},
+	"admin:org": {"write:org", "read:org", "manage_runners:org"},
+	"write:org": {"read:org"},
+}
+
+// checkTokenScopes checks that a classic personal access token has the required scopes. Fine-grained tokens and
+// Github App tokens don't report their scopes, so aren't checked.
+func checkTokenScopes(ctx context.Context, clients *clientFactory, ghURL, token, name string, required ...string) error {
+	u, err := url.Parse(ghURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse url: %w", err)
+	}
+	client, err := clients.NewGitHubClient(u, token)
+	if err != nil {
+		return err
+	}
+	_, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to get the user of %s: %w", name, err)
+	}
+	header, ok := resp.Header["X-Oauth-Scopes"]
+	if !ok {
+		return nil
+	}
+	granted := make(map[string]bool)
+	for _, s := range strings.Split(strings.Join(header, ","), ",") {
+		s = strings.TrimSpace(s)
+		granted[s] = true
+		for _, implied := range impliedScopes[s] {
+			granted[implied] = true
+		}
+	}
+	var missing []string
+	for _, s := range required {
+		if !granted[s] {
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) > 0 {
+		settingsURL := u.Scheme + "://" + u.Host + "/settings/tokens"
+		return fmt.Errorf("%s is missing the %s scopes, add them at %s", name, strings.Join(missing, ", "), settingsURL)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// syncSecuritySettings copies the source repo's Advanced Security, secret scanning, push protection and code scanning
+// default setup settings to the target. Private and internal repos need a Github Advanced Security license, so their
+// settings are only copied if the target org has one.
+func syncSecuritySettings(ctx context.Context, src, tgt repoRef) error {
+	srcRepo, _, err := src.Client.Repositories.Get(ctx, src.Owner, src.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get source repo: %w", err)
+	}
+	tgtRepo, _, err := tgt.Client.Repositories.Get(ctx, tgt.Owner, tgt.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get target repo: %w", err)
+	}
+	public := tgtRepo.GetVisibility() == "public"
+	if !public {
+		licensed, err := hasAdvancedSecurity(ctx, tgt.Client, tgt.Owner)
+		if err != nil {
+			return err
+		}
+		if !licensed {
+			fmt.Printf("Warning: not copying the security settings of %q, because the target org doesn't have Github Advanced Security.\n", tgt.Name)
+			return nil
+		}
+	}
+
+	s, t := srcRepo.GetSecurityAndAnalysis(), tgtRepo.GetSecurityAndAnalysis()
+	var updates github.SecurityAndAnalysis
+	var changed bool
+	// Advanced Security is always enabled for public repos, and can't be set.
+	if status := s.GetAdvancedSecurity().GetStatus(); !public && status != "" && status != t.GetAdvancedSecurity().GetStatus() {
+		updates.AdvancedSecurity = &github.AdvancedSecurity{Status: ptr(status)}
+		changed = true
+	}
+	if status := s.GetSecretScanning().GetStatus(); status != "" && status != t.GetSecretScanning().GetStatus() {
+		updates.SecretScanning = &github.SecretScanning{Status: ptr(status)}
+		changed = true
+	}
+	if status := s.GetSecretScanningPushProtection().GetStatus(); status != "" && status != t.GetSecretScanningPushProtection().GetStatus() {
+		updates.SecretScanningPushProtection = &github.SecretScanningPushProtection{Status: ptr(status)}
+		changed = true
+	}
+	if changed {
+		if _, _, err = tgt.Client.Repositories.Edit(ctx, tgt.Owner, tgt.Name, &github.Repository{SecurityAndAnalysis: &updates}); err != nil {
+			return fmt.Errorf("failed to update security settings: %w", err)
+		}
+	}
+
+	return syncCodeScanningDefaultSetup(ctx, src, tgt)
+}
+
+// syncCodeScanningDefaultSetup enables or disables code scanning default setup on the target to match the source.
+// Servers that don't support default setup are skipped.
+func syncCodeScanningDefaultSetup(ctx context.Context, src, tgt repoRef) error {
+	srcSetup, _, err := src.Client.CodeScanning.GetDefaultSetupConfiguration(ctx, src.Owner, src.Name)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get source code scanning default setup: %w", err)
+	}
+	tgtSetup, _, err := tgt.Client.CodeScanning.GetDefaultSetupConfiguration(ctx, tgt.Owner, tgt.Name)
+	if isNotFound(err) {
+		fmt.Printf("Warning: not copying the code scanning settings of %q, because the target doesn't support default setup.\n", tgt.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get target code scanning default setup: %w", err)
+	}
+	if srcSetup.GetState() == tgtSetup.GetState() {
+		return nil
+	}
+	update := &github.UpdateDefaultSetupConfigurationOptions{
+		State: srcSetup.GetState(),
+	}
+	if update.State == "configured" {
+		update.QuerySuite = srcSetup.QuerySuite
+	}
+	_, _, err = tgt.Client.CodeScanning.UpdateDefaultSetupConfiguration(ctx, tgt.Owner, tgt.Name, update)
+	// Updates are applied asynchronously, and return 202 Accepted.
+	var accepted *github.AcceptedError
+	if err != nil && !errors.As(err, &accepted) {
+		return fmt.Errorf("failed to update code scanning default setup: %w", err)
+	}
+	return nil
+}
+
+// hasAdvancedSecurity returns true if an org can use Github Advanced Security on private repos. On github.com, it's
+// only available with the Enterprise plan. GHES doesn't report a plan, and is licensed for the whole server, so it's
+// assumed to be available, and enabling it fails if it isn't.
+func hasAdvancedSecurity(ctx context.Context, client *github.Client, org string) (bool, error) {
+	o, _, err := client.Organizations.Get(ctx, org)
+	if err != nil {
+		return false, fmt.Errorf("failed to get target org: %w", err)
+	}
+	if o.Plan == nil {
+		return true, nil
+	}
+	return o.GetPlan().GetName() == "enterprise", nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+)
+
+const (
+	releaseOwner = "a-h"
+	releaseRepo  = "copy-github-to-github"
+)
+
+func selfUpdate(args []string) error {
+	fs := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	srcAccessTokenFlag := fs.String("src-token", "", "Personal access token for github.com, used to avoid API rate limiting")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	client, err := new(clientFactory).NewGitHubClient(&url.URL{Scheme: "https", Host: "github.com"}, *srcAccessTokenFlag)
+	if err != nil {
+		return err
+	}
+	release, _, err := client.Repositories.GetLatestRelease(ctx, releaseOwner, releaseRepo)
+	if err != nil {
+		return fmt.Errorf("failed to get latest release: %w", err)
+	}
+
+	asset, err := findReleaseAsset(release.Assets, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return fmt.Errorf("release %s: %w", release.GetTagName(), err)
+	}
+	expectedHash, ok := findSHA256(release.GetBody(), asset.GetName())
+	if !ok {
+		return fmt.Errorf("release %s notes don't contain a SHA-256 checksum for %q", release.GetTagName(), asset.GetName())
+	}
+
+	// Download the asset.
+	fmt.Printf("Downloading %s %s...\n", release.GetTagName(), asset.GetName())
+	rc, _, err := client.Repositories.DownloadReleaseAsset(ctx, releaseOwner, releaseRepo, asset.GetID(), http.DefaultClient)
+	if err != nil {
+		return fmt.Errorf("failed to download release asset: %w", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("failed to read release asset: %w", err)
+	}
+	actualHash := sha256.Sum256(data)
+	if hex.EncodeToString(actualHash[:]) != expectedHash {
+		return fmt.Errorf("checksum mismatch for %q: expected %s, got %x", asset.GetName(), expectedHash, actualHash)
+	}
+	binary := data
+	if strings.HasSuffix(asset.GetName(), ".tar.gz") {
+		if binary, err = extractBinary(data); err != nil {
+			return fmt.Errorf("failed to extract binary from %q: %w", asset.GetName(), err)
+		}
+	}
+
+	// Replace the current executable.
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find current executable: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("failed to resolve current executable: %w", err)
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return fmt.Errorf("failed to stat current executable: %w", err)
+	}
+	tmp := exe + ".tmp"
+	if err = os.WriteFile(tmp, binary, info.Mode()); err != nil {
+		return fmt.Errorf("failed to write new executable: %w", err)
+	}
+	if err = os.Rename(tmp, exe); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace executable: %w", err)
+	}
+	fmt.Printf("Updated %s to %s.\n", exe, release.GetTagName())
+	return nil
+}
+
+// findReleaseAsset finds the asset for the platform, which has a name containing _<os>_<arch>, followed by the end of
+// the name or a file extension, e.g. copy-github-to-github_linux_arm.tar.gz. Matching the whole token stops arm from
+// matching an arm64 asset.
+func findReleaseAsset(assets []*github.ReleaseAsset, goos, goarch string) (asset *github.ReleaseAsset, err error) {
+	token := "_" + goos + "_" + goarch
+	var matches []string
+	for _, a := range assets {
+		name := strings.ToLower(a.GetName())
+		if strings.Contains(name, "sha256") || strings.Contains(name, "checksums") {
+			continue
+		}
+		i := strings.Index(name, token)
+		if i < 0 {
+			continue
+		}
+		if rest := name[i+len(token):]; rest != "" && !strings.HasPrefix(rest, ".") {
+			continue
+		}
+		asset = a
+		matches = append(matches, a.GetName())
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no asset for %s/%s", goos, goarch)
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("more than one asset for %s/%s: %s", goos, goarch, strings.Join(matches, ", "))
+	}
+	return asset, nil
+}
+
+var sha256Pattern = regexp.MustCompile(`\b[0-9a-fA-F]{64}\b`)
+
+// findSHA256 finds the checksum on the line of the release notes that mentions the asset name.
+func findSHA256(notes, assetName string) (hash string, ok bool) {
+	for _, line := range strings.Split(notes, "\n") {
+		if !strings.Contains(line, assetName) {
+			continue
+		}
+		if hash = sha256Pattern.FindString(line); hash != "" {
+			return strings.ToLower(hash), true
+		}
+	}
+	return "", false
+}
+
+func extractBinary(archive []byte) (binary []byte, err error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return binary, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return binary, fmt.Errorf("archive does not contain %q", releaseRepo)
+		}
+		if err != nil {
+			return binary, err
+		}
+		if filepath.Base(hdr.Name) == releaseRepo {
+			return io.ReadAll(tr)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v55/github"
+)
+
+func TestFindReleaseAsset(t *testing.T) {
+	assets := []*github.ReleaseAsset{
+		{Name: ptr("copy-github-to-github_linux_amd64.tar.gz")},
+		{Name: ptr("copy-github-to-github_linux_arm64.tar.gz")},
+		{Name: ptr("copy-github-to-github_linux_arm.tar.gz")},
+		{Name: ptr("copy-github-to-github_linux_arm.tar.gz.sha256")},
+		{Name: ptr("copy-github-to-github_darwin_arm64")},
+		{Name: ptr("checksums.txt")},
+	}
+	tests := []struct {
+		goos, goarch string
+		want         string
+		wantErr      bool
+	}{
+		{goos: "linux", goarch: "amd64", want: "copy-github-to-github_linux_amd64.tar.gz"},
+		{goos: "linux", goarch: "arm", want: "copy-github-to-github_linux_arm.tar.gz"},
+		{goos: "linux", goarch: "arm64", want: "copy-github-to-github_linux_arm64.tar.gz"},
+		{goos: "darwin", goarch: "arm64", want: "copy-github-to-github_darwin_arm64"},
+		{goos: "darwin", goarch: "arm", wantErr: true},
+		{goos: "windows", goarch: "amd64", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos+"/"+tt.goarch, func(t *testing.T) {
+			asset, err := findReleaseAsset(assets, tt.goos, tt.goarch)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", asset.GetName())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if asset.GetName() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, asset.GetName())
+			}
+		})
+	}
+}
+
+func TestFindReleaseAssetRejectsAmbiguousMatches(t *testing.T) {
+	assets := []*github.ReleaseAsset{
+		{Name: ptr("copy-github-to-github_linux_amd64.tar.gz")},
+		{Name: ptr("copy-github-to-github_linux_amd64.zip")},
+	}
+	if _, err := findReleaseAsset(assets, "linux", "amd64"); err == nil {
+		t.Error("expected an error for more than one matching asset")
+	}
+}
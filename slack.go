@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func postSlackReport(ctx context.Context, webhookURL string, summary SyncResult) error {
+	headline := fmt.Sprintf("copy-github-to-github synced %d of %d repos in %v.", summary.Copied, summary.Total, summary.Duration.Round(time.Second))
+	msg := slackMessage{
+		Text: headline,
+		Blocks: []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*" + headline + "*"}},
+		},
+	}
+	if len(summary.Failed) > 0 {
+		failed := new(strings.Builder)
+		fmt.Fprintf(failed, "*%d failed:*\n", len(summary.Failed))
+		for _, f := range summary.Failed {
+			fmt.Fprintf(failed, "• <%s|%s>: %v\n", f.TgtURL, f.Name, f.Err)
+		}
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: failed.String()}})
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to Slack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected Slack response %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// squashHistory replaces the checked out branch with a single root commit containing its tree, like
+// git checkout --orphan && git commit. The commit uses the time of the source commit, so that syncing an unchanged
+// source produces the same commit.
+func squashHistory(repo *git.Repository) error {
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		return fmt.Errorf("HEAD is detached")
+	}
+	branch := head.Target()
+	ref, err := repo.Reference(branch, true)
+	if err != nil {
+		return fmt.Errorf("failed to get branch %q: %w", branch.Short(), err)
+	}
+	headCommit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+	signature := object.Signature{
+		Name:  "copy-github-to-github",
+		Email: "copy-github-to-github@users.noreply.github.com",
+		When:  headCommit.Committer.When,
+	}
+	commit := &object.Commit{
+		Author:    signature,
+		Committer: signature,
+		Message:   fmt.Sprintf("Mirror snapshot %s\n", headCommit.Committer.When.UTC().Format("2006-01-02")),
+		TreeHash:  headCommit.TreeHash,
+	}
+	obj := repo.Storer.NewEncodedObject()
+	if err = commit.Encode(obj); err != nil {
+		return fmt.Errorf("failed to encode commit: %w", err)
+	}
+	h, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return fmt.Errorf("failed to store commit: %w", err)
+	}
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(branch, h)); err != nil {
+		return fmt.Errorf("failed to update branch %q: %w", branch.Short(), err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// resolveSRVURL replaces the host of srcURL with the target of the first SRV record for name. If the lookup fails,
+// srcURL is returned unchanged.
+func resolveSRVURL(name, srcURL string) string {
+	u, err := url.Parse(srcURL)
+	if err != nil {
+		fmt.Printf("Warning: failed to parse src-url %q: %v\n", srcURL, err)
+		return srcURL
+	}
+	_, addrs, err := net.LookupSRV("", "", name)
+	if err != nil || len(addrs) == 0 {
+		fmt.Printf("Warning: failed to resolve SRV record %q, using src-url %q: %v\n", name, srcURL, err)
+		return srcURL
+	}
+	host := strings.TrimSuffix(addrs[0].Target, ".")
+	if addrs[0].Port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(int(addrs[0].Port)))
+	}
+	if u.Scheme == "" {
+		u.Scheme = "https"
+	}
+	u.Host = host
+	return u.String()
+}
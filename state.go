@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is persisted between runs in the file set by -state-file.
+type State struct {
+	// Repos is keyed by source repo URL.
+	Repos map[string]RepoState `json:"repos"`
+	// Deleted is keyed by source repo URL, and records when -dont-recreate-deleted found that a previously copied
+	// repo had been deleted from the target. Remove a repo from it to copy the repo again.
+	Deleted map[string]time.Time `json:"deleted,omitempty"`
+	// SyncStartedAt and SyncCompletedAt are used to tell whether the last sync was interrupted.
+	SyncStartedAt   time.Time `json:"syncStartedAt,omitempty"`
+	SyncCompletedAt time.Time `json:"syncCompletedAt,omitempty"`
+}
+
+type RepoState struct {
+	LastSyncedAt time.Time `json:"lastSyncedAt"`
+}
+
+func NewState() *State {
+	return &State{
+		Repos:   make(map[string]RepoState),
+		Deleted: make(map[string]time.Time),
+	}
+}
+
+// LoadStateFile reads the state file. A missing or corrupted state file is treated as an empty state, since the
+// state can be rebuilt by syncing again.
+func LoadStateFile(fileName string) *State {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("Warning: failed to read state file %q, starting with an empty state: %v\n", fileName, err)
+		}
+		return NewState()
+	}
+	state := NewState()
+	if err = json.Unmarshal(data, state); err != nil {
+		fmt.Printf("Warning: state file %q is corrupted, starting with an empty state: %v\n", fileName, err)
+		return NewState()
+	}
+	if state.Repos == nil {
+		state.Repos = make(map[string]RepoState)
+	}
+	if state.Deleted == nil {
+		state.Deleted = make(map[string]time.Time)
+	}
+	return state
+}
+
+// Save writes the state atomically, so that a crash part way through writing can't leave a corrupted state file.
+func (s *State) Save(fileName string) (err error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return writeFileAtomic(fileName, data)
+}
+
+// writeFileAtomic writes the data to a temporary file in the same directory, then renames it over the existing file,
+// so that readers never see a partially written file.
+func writeFileAtomic(fileName string, data []byte) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err = os.Rename(f.Name(), fileName); err != nil {
+		return fmt.Errorf("failed to replace %q: %w", fileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var ciConfigPaths = []string{
+	".github/workflows",
+	".travis.yml",
+	"Jenkinsfile",
+	".gitlab-ci.yml",
+	".circleci",
+	"azure-pipelines.yml",
+}
+
+// stripCIConfigs removes CI configuration files from the checked out branch, and commits the change on top of it.
+func stripCIConfigs(repo *git.Repository, dir string) (stripped bool, err error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	for _, p := range ciConfigPaths {
+		if _, err = os.Stat(filepath.Join(dir, p)); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return false, fmt.Errorf("failed to stat %q: %w", p, err)
+		}
+		if _, err = wt.Remove(p); err != nil {
+			return false, fmt.Errorf("failed to remove %q: %w", p, err)
+		}
+		stripped = true
+	}
+	if !stripped {
+		return false, nil
+	}
+
+	// Use the time of the source commit, so that syncing an unchanged source produces the same commit.
+	head, err := repo.Head()
+	if err != nil {
+		return false, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return false, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+	signature := &object.Signature{
+		Name:  "copy-github-to-github",
+		Email: "copy-github-to-github@users.noreply.github.com",
+		When:  headCommit.Committer.When,
+	}
+	_, err = wt.Commit("Remove CI configuration from mirror", &git.CommitOptions{
+		Author:    signature,
+		Committer: signature,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to commit: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// createTgtOrgIfMissing creates the target org using the GHES site admin API if it doesn't already exist.
+func createTgtOrgIfMissing(ctx context.Context, clients *clientFactory, tgtURL, tgtAccessToken, adminUser string) error {
+	u, err := url.Parse(tgtURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse url: %w", err)
+	}
+	client, err := clients.NewGitHubClient(u, tgtAccessToken)
+	if err != nil {
+		return err
+	}
+	org := strings.Split(strings.Trim(u.Path, "/"), "/")[0]
+	_, _, err = client.Organizations.Get(ctx, org)
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) {
+		return fmt.Errorf("failed to get target org %q: %w", org, err)
+	}
+	fmt.Printf("Target org %q doesn't exist, creating it with %q as admin.\n", org, adminUser)
+	if _, _, err = client.Admin.CreateOrg(ctx, &github.Organization{Login: ptr(org)}, adminUser); err != nil {
+		return fmt.Errorf("failed to create target org %q: %w", org, err)
+	}
+	return nil
+}
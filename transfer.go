@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// gitProgressObjects matches the object count of a phase, e.g. "(123/270)", or the summary, e.g. "Total 270".
+	gitProgressObjects = regexp.MustCompile(`\(\d+/(\d+)\)|^Total (\d+)`)
+	// gitProgressBytes matches the amount transferred, e.g. "1.50 MiB | 2.00 MiB/s".
+	gitProgressBytes = regexp.MustCompile(`([\d.]+) (bytes|KiB|MiB|GiB)(?: \||,|$)`)
+)
+
+var byteUnits = map[string]float64{
+	"bytes": 1,
+	"KiB":   1 << 10,
+	"MiB":   1 << 20,
+	"GiB":   1 << 30,
+}
+
+// transferStats is the size of a clone or push, as reported by the git server's progress messages.
+type transferStats struct {
+	Objects int
+	Bytes   int64
+}
+
+// parse updates the stats from a progress message. Messages are cumulative, so the largest values are kept.
+func (s *transferStats) parse(line string) {
+	if m := gitProgressObjects.FindStringSubmatch(line); m != nil {
+		n, _ := strconv.Atoi(m[1] + m[2])
+		s.Objects = max(s.Objects, n)
+	}
+	if m := gitProgressBytes.FindStringSubmatch(line); m != nil {
+		f, _ := strconv.ParseFloat(m[1], 64)
+		s.Bytes = max(s.Bytes, int64(f*byteUnits[m[2]]))
+	}
+}
+
+// logTransfer logs the size and throughput of a clone or push of a repo.
+func logTransfer(repo, direction string, stats transferStats, d time.Duration) {
+	var mbPerSecond float64
+	if d > 0 {
+		mbPerSecond = float64(stats.Bytes) / 1e6 / d.Seconds()
+	}
+	slog.Info("Git transfer complete",
+		slog.String("repo", repo),
+		slog.String("direction", direction),
+		slog.Int("objects", stats.Objects),
+		slog.Int64("bytes", stats.Bytes),
+		slog.Duration("duration", d),
+		slog.String("mb_per_second", strconv.FormatFloat(mbPerSecond, 'f', 2, 64)),
+	)
+}
+
+// packSize returns the total size of the pack files of a clone. go-git stores the pack received from the server
+// as-is, so it's the number of bytes transferred by a clone when the server doesn't report it.
+func packSize(dir string) (size int64) {
+	entries, err := os.ReadDir(filepath.Join(dir, ".git", "objects", "pack"))
+	if err != nil {
+		return 0
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".pack") {
+			continue
+		}
+		if fi, err := e.Info(); err == nil {
+			size += fi.Size()
+		}
+	}
+	return size
+}
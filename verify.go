@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// mirrorCheckOptions are the flags shared by the sub-commands that compare mirrors with their source.
+type mirrorCheckOptions struct {
+	SrcAccessToken  string
+	SrcURL          string
+	SrcType         string
+	SrcOrgType      string
+	SrcRepoListFile string
+	TgtAccessToken  string
+	TgtURL          string
+	RepoMapFile     string
+	Clients         clientOptions
+	// RepoMap is read from RepoMapFile by Validate.
+	RepoMap repoMap
+}
+
+func (o *mirrorCheckOptions) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.SrcAccessToken, "src-token", "", "Personal access token for reading from the source")
+	fs.StringVar(&o.SrcURL, "src-url", "", "URL of source organization or repo, e.g. https://github.com/org")
+	fs.StringVar(&o.SrcType, "src-type", "github", "Type of the source, can be github or bitbucket")
+	fs.StringVar(&o.SrcOrgType, "src-org-type", "auto", "Type of the src-url owner, can be auto, org or user")
+	fs.StringVar(&o.SrcRepoListFile, "src-repo-list-file", "", "Path to a JSON or CSV file containing the names and URLs of the source repos, used instead of listing the repos at src-url")
+	fs.StringVar(&o.TgtAccessToken, "tgt-token", "", "Personal access token for reading from the target")
+	fs.StringVar(&o.TgtURL, "tgt-url", "", "URL of target org, e.g. https://github.enterprise.com/org")
+	fs.StringVar(&o.RepoMapFile, "repo-map-file", "", "Path to a JSON file mapping source repo names to target repos in the form org/name")
+	o.Clients.RegisterFlags(fs)
+}
+
+func (o *mirrorCheckOptions) Validate() (errors []string) {
+	if o.SrcAccessToken == "" {
+		errors = append(errors, "Missing src-token flag")
+	}
+	if o.SrcURL == "" && o.SrcRepoListFile == "" {
+		errors = append(errors, "Missing src-url or src-repo-list-file flag")
+	}
+	if o.TgtAccessToken == "" {
+		errors = append(errors, "Missing tgt-token flag")
+	}
+	if o.TgtURL == "" {
+		errors = append(errors, "Missing tgt-url flag")
+	}
+	if msg := isOneOf(o.SrcType, "github", "bitbucket"); msg != "" {
+		errors = append(errors, "src-type: "+msg)
+	}
+	if msg := isOneOf(o.SrcOrgType, "auto", "org", "user"); msg != "" {
+		errors = append(errors, "src-org-type: "+msg)
+	}
+	errors = append(errors, o.Clients.Validate()...)
+	if o.RepoMapFile != "" {
+		var err error
+		if o.RepoMap, err = readRepoMapFile(o.RepoMapFile); err != nil {
+			errors = append(errors, "repo-map-file: "+err.Error())
+		}
+	}
+	return errors
+}
+
+// ListRepos lists the source repos.
+func (o mirrorCheckOptions) ListRepos(ctx context.Context, clients *clientFactory) (repos []Repo, err error) {
+	if o.SrcRepoListFile != "" {
+		repos, err = readRepoListFile(o.SrcRepoListFile)
+	} else if o.SrcType == "bitbucket" {
+		repos, err = listBitbucketRepos(ctx, clients, o.SrcURL, o.SrcAccessToken)
+	} else {
+		repos, err = listRepos(ctx, clients, o.SrcURL, o.SrcAccessToken, o.SrcOrgType, false)
+	}
+	if err != nil {
+		return repos, fmt.Errorf("failed to list repos: %w", err)
+	}
+	return repos, nil
+}
+
+// verify compares the branches and tags of each source repo with its mirror, without pushing anything.
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	var opts mirrorCheckOptions
+	opts.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if errors := opts.Validate(); len(errors) > 0 {
+		return fmt.Errorf("invalid or missing params:\n -%s", strings.Join(errors, "\n -"))
+	}
+
+	if err := opts.Clients.CheckConnectivity(opts.SrcURL, opts.TgtURL); err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer cancel()
+
+	clients, err := opts.Clients.NewClientFactory()
+	if err != nil {
+		return err
+	}
+	repos, err := opts.ListRepos(ctx, clients)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		tgt, err := opts.RepoMap.TargetURL(repo, opts.TgtURL)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite URL: %w", err)
+		}
+		discrepancies, err := verifyMirror(ctx, repo.URL, opts.SrcAccessToken, tgt, opts.TgtAccessToken)
+		if err != nil {
+			fmt.Printf("%q: failed to verify: %v\n", repo.Name, err)
+			failed++
+			continue
+		}
+		if len(discrepancies) == 0 {
+			fmt.Printf("%q: OK.\n", repo.Name)
+			continue
+		}
+		failed++
+		fmt.Printf("%q: %d discrepancies:\n", repo.Name, len(discrepancies))
+		for _, d := range discrepancies {
+			fmt.Printf("  %v\n", d)
+		}
+	}
+	fmt.Printf("Verified %d of %d repos.\n", len(repos)-failed, len(repos))
+	if failed > 0 {
+		return fmt.Errorf("%d repos don't match the source", failed)
+	}
+	return nil
+}
+
+type refDiscrepancy struct {
+	Ref string
+	// Problem is one of "missing", "different" or "extra".
+	Problem string
+	Src     plumbing.Hash
+	Tgt     plumbing.Hash
+}
+
+func (d refDiscrepancy) String() string {
+	switch d.Problem {
+	case "missing":
+		return fmt.Sprintf("%s: missing from target, source is %v", d.Ref, d.Src)
+	case "extra":
+		return fmt.Sprintf("%s: not in source, target is %v", d.Ref, d.Tgt)
+	}
+	return fmt.Sprintf("%s: source is %v, target is %v", d.Ref, d.Src, d.Tgt)
+}
+
+// verifyMirror compares the branches and tags of the source and target. Other refs, such as Github's refs/pull/*, are
+// not copied, so are ignored.
+func verifyMirror(ctx context.Context, src, srcAccessToken, tgt, tgtAccessToken string) (discrepancies []refDiscrepancy, err error) {
+	srcRefs, err := listRemoteRefs(ctx, src, srcAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	tgtRefs, err := listRemoteRefs(ctx, tgt, tgtAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	for name, srcHash := range srcRefs {
+		if !isCopiedRef(name) {
+			continue
+		}
+		tgtHash, ok := tgtRefs[name]
+		if !ok {
+			discrepancies = append(discrepancies, refDiscrepancy{Ref: name, Problem: "missing", Src: srcHash})
+			continue
+		}
+		if tgtHash != srcHash {
+			discrepancies = append(discrepancies, refDiscrepancy{Ref: name, Problem: "different", Src: srcHash, Tgt: tgtHash})
+		}
+	}
+	for name, tgtHash := range tgtRefs {
+		if !isCopiedRef(name) {
+			continue
+		}
+		if _, ok := srcRefs[name]; !ok {
+			discrepancies = append(discrepancies, refDiscrepancy{Ref: name, Problem: "extra", Tgt: tgtHash})
+		}
+	}
+	sort.Slice(discrepancies, func(i, j int) bool {
+		return discrepancies[i].Ref < discrepancies[j].Ref
+	})
+	return discrepancies, nil
+}
+
+func isCopiedRef(name string) bool {
+	ref := plumbing.ReferenceName(name)
+	return ref.IsBranch() || ref.IsTag()
+}
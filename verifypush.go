@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-github/v55/github"
+)
+
+// verifyPushedBranches checks that each local branch has the same commit SHA on the target.
+func verifyPushedBranches(ctx context.Context, client *github.Client, repo *git.Repository, owner, name string) error {
+	branches, err := repo.Branches()
+	if err != nil {
+		return fmt.Errorf("failed to list local branches: %w", err)
+	}
+	var mismatches []string
+	err = branches.ForEach(func(ref *plumbing.Reference) error {
+		branch, _, err := client.Repositories.GetBranch(ctx, owner, name, ref.Name().Short(), true)
+		if err != nil {
+			return fmt.Errorf("failed to get target branch %q: %w", ref.Name().Short(), err)
+		}
+		if sha := branch.GetCommit().GetSHA(); sha != ref.Hash().String() {
+			mismatches = append(mismatches, fmt.Sprintf("%s (local %s, target %s)", ref.Name().Short(), ref.Hash(), sha))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("target branches don't match the pushed commits: %s", strings.Join(mismatches, ", "))
+	}
+	return nil
+}
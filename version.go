@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// serverVersion is the version of a Github Enterprise Server. github.com doesn't have a version, and supports all
+// features.
+type serverVersion struct {
+	Enterprise bool
+	Major      int
+	Minor      int
+	Patch      int
+	// Unknown is true if the server didn't report a version, e.g. GHE.com data residency hosts. It's treated as the
+	// latest version, like github.com.
+	Unknown bool
+}
+
+func (v serverVersion) String() string {
+	if v.Unknown {
+		return "unknown (assuming latest)"
+	}
+	if !v.Enterprise {
+		return "github.com"
+	}
+	return fmt.Sprintf("GHES %d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast returns true if the server is github.com, or a GHES version greater than or equal to major.minor.
+func (v serverVersion) AtLeast(major, minor int) bool {
+	if !v.Enterprise {
+		return true
+	}
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+// feature is an API feature that isn't available in all GHES versions.
+type feature string
+
+const (
+	featureRepoVisibility feature = "repo visibility"
+	featureRenameBranch   feature = "rename branch"
+	featureSCIM           feature = "SCIM provisioning"
+	featureRulesets       feature = "repository rulesets"
+)
+
+// featureVersions is the minimum GHES major and minor version that supports each feature.
+var featureVersions = map[feature][2]int{
+	featureRepoVisibility: {3, 0},
+	featureRenameBranch:   {3, 1},
+	featureSCIM:           {3, 6},
+	featureRulesets:       {3, 11},
+}
+
+// Supports returns true if the server supports the feature.
+func (v serverVersion) Supports(f feature) bool {
+	min, ok := featureVersions[f]
+	if !ok {
+		return true
+	}
+	return v.AtLeast(min[0], min[1])
+}
+
+// requires returns a description of the minimum version required for the feature, e.g. "GHES 3.1 or later".
+func requires(f feature) string {
+	min := featureVersions[f]
+	return fmt.Sprintf("GHES %d.%d or later", min[0], min[1])
+}
+
+func parseServerVersion(s string) (v serverVersion, err error) {
+	v.Enterprise = true
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	ints := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i := 0; i < len(parts) && i < len(ints); i++ {
+		if *ints[i], err = strconv.Atoi(parts[i]); err != nil {
+			return v, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+	}
+	return v, nil
+}
+
+// detectServerVersion reads the installed_version from the /meta endpoint of a GHES server. Servers that aren't GHES,
+// and proxies, may not include it, so the version is unknown, and all features are assumed to be supported.
+func detectServerVersion(ctx context.Context, clients *clientFactory, ghURL, token string) (v serverVersion, err error) {
+	u, err := url.Parse(ghURL)
+	if err != nil {
+		return v, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if strings.EqualFold(u.Hostname(), "github.com") {
+		return v, nil
+	}
+	client, err := clients.NewGitHubClient(u, token)
+	if err != nil {
+		return v, err
+	}
+	req, err := client.NewRequest("GET", "meta", nil)
+	if err != nil {
+		return v, fmt.Errorf("failed to create meta request: %w", err)
+	}
+	var meta struct {
+		InstalledVersion string `json:"installed_version"`
+	}
+	if _, err = client.Do(ctx, req, &meta); err != nil {
+		return v, fmt.Errorf("failed to get meta: %w", err)
+	}
+	if meta.InstalledVersion == "" {
+		fmt.Printf("Warning: %q didn't report its version, assuming it supports the latest API features.\n", u.Host)
+		return serverVersion{Unknown: true}, nil
+	}
+	return parseServerVersion(meta.InstalledVersion)
+}
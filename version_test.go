@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDetectServerVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		meta string
+		want serverVersion
+	}{
+		{
+			name: "GHES",
+			meta: `{"installed_version":"3.9.2"}`,
+			want: serverVersion{Enterprise: true, Major: 3, Minor: 9, Patch: 2},
+		},
+		{
+			name: "no installed version",
+			meta: `{"verifiable_password_authentication":false}`,
+			want: serverVersion{Unknown: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v3/meta" {
+					t.Errorf("unexpected path %q", r.URL.Path)
+				}
+				w.Write([]byte(tt.meta))
+			}))
+			defer srv.Close()
+
+			v, err := detectServerVersion(context.Background(), new(clientFactory), srv.URL+"/org", "token")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, v)
+			}
+			if !v.Supports(featureRulesets) && tt.want.Unknown {
+				t.Error("expected an unknown version to support all features")
+			}
+		})
+	}
+}
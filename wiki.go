@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// wikiSuffix is appended to the name of a repo to get the name of its wiki's git repo. Github doesn't allow repo
+// names to end with it, so it can be used to tell wikis apart.
+const wikiSuffix = ".wiki"
+
+func isWikiName(name string) bool {
+	return strings.HasSuffix(name, wikiSuffix)
+}
+
+// wikiRepo returns the wiki of the repo, which is mirrored like any other repo.
+func wikiRepo(r Repo) Repo {
+	return Repo{
+		Name:      r.Name + wikiSuffix,
+		URL:       r.URL + wikiSuffix,
+		UpdatedAt: r.UpdatedAt,
+		CreatedAt: r.CreatedAt,
+		Archived:  r.Archived,
+		Topics:    r.Topics,
+	}
+}
+
+// groupWikis groups each repo with its wiki, if the wiki is in the list, keeping the order of the repos. A wiki whose
+// repo isn't in the list, e.g. because the repo was skipped by -resume, is in a group of its own.
+func groupWikis(repos []Repo) (groups [][]Repo) {
+	index := make(map[string]int, len(repos))
+	for _, r := range repos {
+		if !isWikiName(r.Name) {
+			index[r.Name] = len(groups)
+			groups = append(groups, []Repo{r})
+		}
+	}
+	for _, r := range repos {
+		if !isWikiName(r.Name) {
+			continue
+		}
+		if i, ok := index[strings.TrimSuffix(r.Name, wikiSuffix)]; ok {
+			groups[i] = append(groups[i], r)
+			continue
+		}
+		groups = append(groups, []Repo{r})
+	}
+	return groups
+}
+
+// enableWiki turns on the wiki of the target repo, so that the wiki's git repo can be pushed to.
+func enableWiki(ctx context.Context, client *github.Client, owner, name string) error {
+	repo, _, err := client.Repositories.Get(ctx, owner, name)
+	if err != nil {
+		return fmt.Errorf("failed to get target repo of wiki: %w", err)
+	}
+	if repo.GetHasWiki() {
+		return nil
+	}
+	if _, _, err = client.Repositories.Edit(ctx, owner, name, &github.Repository{HasWiki: ptr(true)}); err != nil {
+		return fmt.Errorf("failed to enable wiki: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGroupWikis(t *testing.T) {
+	a := Repo{Name: "a", URL: "https://github.com/org/a"}
+	b := Repo{Name: "b", URL: "https://github.com/org/b"}
+	// The wiki of a repo that isn't in the list, e.g. because it was skipped by -resume.
+	orphan := wikiRepo(Repo{Name: "c", URL: "https://github.com/org/c"})
+
+	groups := groupWikis([]Repo{a, wikiRepo(a), orphan, b})
+
+	want := [][]Repo{{a, wikiRepo(a)}, {b}, {orphan}}
+	if fmt.Sprint(groups) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, groups)
+	}
+}
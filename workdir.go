@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// persistentCloneDir returns the directory under workDir used for the clone of src, e.g. <workDir>/github.com/org/repo.
+func persistentCloneDir(workDir, src string) (dir string, err error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	p := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if p == "" || strings.Contains(p, "..") {
+		return "", fmt.Errorf("invalid repo path %q", u.Path)
+	}
+	return filepath.Join(workDir, u.Hostname(), filepath.FromSlash(p)), nil
+}
+
+// updateClone opens a clone left by a previous sync, and fetches from the source, so that only new objects are
+// downloaded. Local changes made by the previous sync, such as commits that strip CI configs, are discarded by
+// resetting the local branch to the source. Refs that have been deleted from the source are deleted locally, since
+// go-git can't prune when fetching.
+func updateClone(ctx context.Context, dir, src, srcAccessToken string, opts copyOptions, name string) (repo *git.Repository, err error) {
+	if repo, err = git.PlainOpen(dir); err != nil {
+		return nil, err
+	}
+	auth := &http.BasicAuth{
+		Username: gitUsername(src),
+		Password: srcAccessToken,
+	}
+	err = withGitTimeout(ctx, opts.GitTimeout, func(ctx context.Context) error {
+		return repo.FetchContext(ctx, &git.FetchOptions{
+			RemoteName: "origin",
+			RemoteURL:  src,
+			RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+			Auth:       auth,
+			Tags:       git.AllTags,
+			Force:      true,
+			Progress:   opts.gitProgress(newGitProgressWriter(name)),
+		})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("failed to fetch: %w", err)
+	}
+
+	var srcRefs map[string]plumbing.Hash
+	err = withGitTimeout(ctx, opts.GitTimeout, func(ctx context.Context) (err error) {
+		srcRefs, err = listRemoteRefs(ctx, src, srcAccessToken)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+	var stale []plumbing.ReferenceName
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name()
+		switch {
+		case name.IsTag():
+			if _, ok := srcRefs[name.String()]; !ok {
+				stale = append(stale, name)
+			}
+		case name.IsRemote():
+			branch := strings.TrimPrefix(name.String(), "refs/remotes/origin/")
+			if _, ok := srcRefs[plumbing.NewBranchReferenceName(branch).String()]; !ok {
+				stale = append(stale, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range stale {
+		if err = repo.Storer.RemoveReference(name); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+
+	// Reset the checked out branch to the source.
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		return nil, fmt.Errorf("HEAD is detached")
+	}
+	remote, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", head.Target().Short()), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source branch %q: %w", head.Target().Short(), err)
+	}
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(head.Target(), remote.Hash())); err != nil {
+		return nil, fmt.Errorf("failed to update branch %q: %w", head.Target().Short(), err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err = wt.Reset(&git.ResetOptions{Commit: remote.Hash(), Mode: git.HardReset}); err != nil {
+		return nil, fmt.Errorf("failed to reset worktree: %w", err)
+	}
+	return repo, nil
+}